@@ -32,23 +32,39 @@ import (
 	"regexp"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
 	var umod, umodeExecute, umodeInternal, umodeInternalExecute bool
-	var org, dep, graph, output, outputTemplate, outputEncode, cron string
-	var verbose, warnUnsyncDev, warnGoVersion, distinct bool
-	var maxConn int
+	var forge, githubToken, githubApiUrl, githubRawUrl, githubHost, dockerhubUrl, gitlabToken, gitlabUrl, giteaToken, giteaUrl, localDir, localHost string
+	var org, dep, graph, output, outputTemplate, outputEncode, cron, cacheDir, stateFile, saveSnapshot, loadSnapshot, diffFile, distinctState, historyFile, at string
+	var minLag string
+	var verbose, warnUnsyncDev, warnGoVersion, distinct, includePrerelease, failOnRepoError, clearCache, usageAll bool
+	var maxConn, maxRetries int
+	var timeout, requestTimeout, digestInterval time.Duration
 
 	flag.BoolVar(&umod, "u", false, "update mode: check local repository for updates and print go get commands")
 	flag.BoolVar(&umodeInternal, "ui", false, "update mode: check local repository for updates and print go get commands (without go get -u)")
 	flag.BoolVar(&umodeExecute, "ux", false, "update mode: check local repository for updates and execute go get commands")
 	flag.BoolVar(&umodeInternalExecute, "uix", false, "update mode: check local repository for updates and execute go get commands (without go get -u)")
 
-	flag.StringVar(&org, "org", "", "github org to be scanned")
+	flag.StringVar(&org, "org", "", "github org or gitlab group to be scanned")
+	flag.StringVar(&forge, "forge", "", "github, gitlab, gitea or forgejo; defaults to github or is derived from the url arg")
+	flag.StringVar(&githubToken, "github_token", "", "github token (optional); enables private repositories and higher rate limits")
+	flag.StringVar(&githubApiUrl, "github_api_url", "", "github api url (optional); used for GitHub Enterprise, e.g. https://github.example.com/api/v3/")
+	flag.StringVar(&githubRawUrl, "github_raw_url", "", "github raw content url (optional); defaults to https://raw.githubusercontent.com/ or <host>/raw/ if github_api_url is set")
+	flag.StringVar(&githubHost, "github_host", "", "expected host of module names for GitHub Enterprise (optional); defaults to the host of github_api_url")
+	flag.StringVar(&gitlabToken, "gitlab_token", "", "gitlab token (optional); enables private repositories")
+	flag.StringVar(&gitlabUrl, "gitlab_url", "", "gitlab base url, e.g. https://gitlab.example.com; derived from the url arg if not set")
+	flag.StringVar(&giteaToken, "gitea_token", "", "gitea/forgejo token (optional); enables private repositories")
+	flag.StringVar(&giteaUrl, "gitea_url", "", "gitea/forgejo base url, e.g. https://gitea.example.com; derived from the url arg if not set")
+	flag.StringVar(&localDir, "local", "", "directory containing checkouts of the org repositories; scans them offline instead of a remote org")
+	flag.StringVar(&localHost, "local_host", pkg.GithubUrl, "expected host of module names when scanning a local directory")
+	flag.StringVar(&dockerhubUrl, "dockerhub_url", pkg.DockerhubGolangTagsUrl, "docker hub url listing golang image tags; used to find the latest go version")
 	flag.StringVar(&output, "output", "", "output, defaults to std-out; may be a file location or a url")
 	flag.StringVar(&outputTemplate, "output_template", "{{.Output}}", "template for output")
-	flag.StringVar(&outputEncode, "output_encode", "plain/text", "encode output as plain/text or application/json")
+	flag.StringVar(&outputEncode, "output_encode", "plain/text", "encode output as plain/text, application/json (plain text report as json string), application/vnd.mopher+json (structured report), text/markdown (tables for issues and pr comments), text/html (self-contained dashboard), application/sarif+json (code scanning) or application/junit+xml (ci test reports)")
 	flag.StringVar(&dep, "dep", "", "dependency to be scanned for in org (optional)")
 	flag.StringVar(&graph, "graph", "", "output file for plantuml dependency graph (optional)")
 	flag.BoolVar(&verbose, "graph_verbose", false, "include none org dependencies in plantuml")
@@ -56,7 +72,24 @@ func main() {
 	flag.BoolVar(&distinct, "distinct", false, "only output if output has changed (useful for cron jobs)")
 	flag.BoolVar(&warnUnsyncDev, "warn_unsync_dev", true, "warn if dev and master/main branches are not at the same commit")
 	flag.BoolVar(&warnGoVersion, "warn_go_version", false, "warn if used go version is not the newest version")
+	flag.StringVar(&minLag, "min_lag", "", "only warn about outdated org dependencies that are at least behind-patch, behind-minor or behind-major (optional); unknown lags are always reported")
+	flag.BoolVar(&includePrerelease, "include_prerelease", false, "use pre-release tags (e.g. v2.0.0-beta) as latest version of org modules")
+	flag.BoolVar(&failOnRepoError, "fail_on_repo_error", false, "abort if a repository could not be checked; by default such repositories are listed in the output")
+	flag.DurationVar(&timeout, "timeout", 0, "max duration of a whole scan, e.g. 10m (optional)")
+	flag.DurationVar(&requestTimeout, "request_timeout", 30*time.Second, "max duration of a single http request or git ls-remote")
+	flag.StringVar(&cacheDir, "cache_dir", "", "directory for cached http responses (optional); cached responses are revalidated by etag or last-modified")
+	flag.BoolVar(&clearCache, "clear_cache", false, "remove all cached http responses from cache_dir before scanning")
+	flag.StringVar(&stateFile, "state_file", "", "file to persist scan results (optional); repositories without pushes since the last scan are not fetched again")
+	flag.StringVar(&saveSnapshot, "save_snapshot", "", "file to store the loaded state as json snapshot (optional)")
+	flag.StringVar(&loadSnapshot, "load_snapshot", "", "json snapshot file (see save_snapshot) used instead of scanning an org; works offline")
+	flag.StringVar(&diffFile, "diff_file", "", "snapshot file of the previous run (optional); if set, only changes since the previous run are written (useful for cron jobs)")
+	flag.StringVar(&distinctState, "distinct_state", "", "file to persist findings (optional); if set, only warnings that are new or resolved since the previous run are written")
+	flag.DurationVar(&digestInterval, "digest_interval", 0, "with distinct_state: write the full report if this duration has passed since the last full report, e.g. 168h (optional)")
+	flag.StringVar(&historyFile, "history_file", "", "json lines file; every run appends its findings and per module lags (optional); read by 'mopher history'")
+	flag.BoolVar(&usageAll, "usage_all", false, "'mopher usage' lists non-org dependencies too")
+	flag.StringVar(&at, "at", "", "scan the org as it was at this date (e.g. 2026-03-01, end of day in UTC) or RFC 3339 time (optional); clones every repository. the go version of 'warn_go_version' is still today's latest go release")
 	flag.IntVar(&maxConn, "max_conn", 25, "max parallel connections to github")
+	flag.IntVar(&maxRetries, "max_retries", 5, "max retries of an http request on network errors, 5xx responses and rate limits")
 
 	flag.BoolFunc("debug", "enables debug logs", func(s string) error {
 		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
@@ -64,11 +97,25 @@ func main() {
 	})
 	flag.Parse()
 
+	//sub-commands may be followed by more flags
+	command := ""
+	if flag.NArg() > 0 && (flag.Arg(0) == "diff" || flag.Arg(0) == "history" || flag.Arg(0) == "usage") {
+		command = flag.Arg(0)
+		err := flag.CommandLine.Parse(flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	//set args by environment variable, if the environment variable is not empty
 	flag.VisitAll(func(f *flag.Flag) {
 		env := os.Getenv(argNameToEnvName(f.Name))
 		if env != "" {
-			fmt.Printf("set arg %v by env %v\n", f.Name, env)
+			if strings.HasSuffix(f.Name, "_token") {
+				fmt.Printf("set arg %v by env\n", f.Name)
+			} else {
+				fmt.Printf("set arg %v by env %v\n", f.Name, env)
+			}
 			err := f.Value.Set(env)
 			if err != nil {
 				log.Fatal(err)
@@ -76,85 +123,180 @@ func main() {
 		}
 	})
 
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
 	if umod || umodeExecute || umodeInternal || umodeInternalExecute {
-		runUpdateMode(umodeExecute || umodeInternalExecute, umodeInternal || umodeInternalExecute)
+		runUpdateMode(ctx, umodeExecute || umodeInternalExecute, umodeInternal || umodeInternalExecute)
 		return
 	}
 
-	args := flag.Args()
-	switch len(args) {
-	case 0:
-		if org == "" {
-			tempOrg, tempDep, err := getParamsFromDir(".")
-			if err != nil {
-				log.Fatal(err)
-				return
-			}
-			if org == "" {
-				org = tempOrg
-			}
-			if dep == "" {
-				dep = tempDep
-			}
+	switch pkg.Lag(minLag) {
+	case "", pkg.LagBehindPatch, pkg.LagBehindMinor, pkg.LagBehindMajor:
+	default:
+		log.Fatal("unexpected min_lag value ", minLag)
+		return
+	}
+
+	if clearCache {
+		if cacheDir == "" {
+			log.Fatal("clear_cache needs cache_dir")
+			return
 		}
-	case 1:
-		tempOrg, tempDep, err := getParamsFromArg(args[0])
+		err := pkg.ClearHttpCache(cacheDir)
 		if err != nil {
 			log.Fatal(err)
 			return
 		}
-		if org == "" {
-			org = tempOrg
+	}
+
+	var atTime time.Time
+	if at != "" {
+		var err error
+		atTime, err = pkg.ParseTimeTravelDate(at)
+		if err != nil {
+			log.Fatal("unexpected at value ", at, ": ", err)
+			return
+		}
+	}
+
+	var params scanParams
+	var err error
+	args := flag.Args()
+	var diffFiles []string
+	if command == "diff" {
+		if len(args) != 2 {
+			log.Fatal("diff expects two snapshot files")
+			return
 		}
-		if dep == "" {
-			dep = tempDep
+		diffFiles, args = args, nil
+	}
+	switch len(args) {
+	case 0:
+		if org == "" && loadSnapshot == "" && command != "diff" && command != "history" {
+			params, err = getParamsFromDir(".", forge)
+			if command == "usage" {
+				//the usage export lists every dependency unless the dep flag or a module arg is given
+				params.Dep = ""
+			}
 		}
+	case 1:
+		params, err = getParamsFromArg(args[0], forge)
 	default:
 		log.Fatal("unexpected args", args)
 		return
 	}
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if org == "" {
+		org = params.Org
+	}
+	if dep == "" {
+		dep = params.Dep
+	}
+	if localDir != "" {
+		forge = pkg.ForgeLocal
+	}
+	if forge == "" {
+		forge = params.Forge
+	}
+	if gitlabUrl == "" && forge == pkg.ForgeGitlab {
+		gitlabUrl = params.BaseUrl
+	}
+	if giteaUrl == "" && (forge == pkg.ForgeGitea || forge == pkg.ForgeForgejo) {
+		giteaUrl = params.BaseUrl
+	}
 
 	config := pkg.MopherConfig{
-		Output:         output,
-		OutputTemplate: outputTemplate,
-		OutputEncode:   outputEncode,
-		Org:            org,
-		MaxConn:        maxConn,
-		Graph:          graph,
-		Verbose:        verbose,
-		Dep:            dep,
-		WarnUnsyncDev:  warnUnsyncDev,
-		WarnGoVersion:  warnGoVersion,
+		Output:               output,
+		OutputTemplate:       outputTemplate,
+		OutputEncode:         outputEncode,
+		Org:                  org,
+		Forge:                forge,
+		GithubToken:          githubToken,
+		GithubApiUrl:         githubApiUrl,
+		GithubRawUrl:         githubRawUrl,
+		GithubHost:           githubHost,
+		GitlabToken:          gitlabToken,
+		GitlabUrl:            gitlabUrl,
+		GiteaToken:           giteaToken,
+		GiteaUrl:             giteaUrl,
+		LocalDir:             localDir,
+		LocalHost:            localHost,
+		DockerhubUrl:         dockerhubUrl,
+		MaxConn:              maxConn,
+		Graph:                graph,
+		Verbose:              verbose,
+		Dep:                  dep,
+		WarnUnsyncDev:        warnUnsyncDev,
+		WarnGoVersion:        warnGoVersion,
+		MinLag:               pkg.Lag(minLag),
+		IncludePrerelease:    includePrerelease,
+		FailOnRepoErrors:     failOnRepoError,
+		Timeout:              timeout,
+		RequestTimeout:       requestTimeout,
+		MaxRetries:           maxRetries,
+		CacheDir:             cacheDir,
+		StateFile:            stateFile,
+		SaveSnapshot:         saveSnapshot,
+		LoadSnapshot:         loadSnapshot,
+		DiffFile:             diffFile,
+		DistinctStateFile:    distinctState,
+		DigestInterval:       digestInterval,
+		HistoryFile:          historyFile,
+		UsageAllDependencies: usageAll,
+		At:                   atTime,
 	}
 
 	if distinct {
 		config.PreOutputHook = pkg.GetDistinctHook()
 	}
 
-	slog.Debug("Startup", "config", config)
-
-	if cron != "" {
-		ctx, cancel := context.WithCancel(context.Background())
+	loggedConfig := config
+	if loggedConfig.GithubToken != "" {
+		loggedConfig.GithubToken = "***"
+	}
+	if loggedConfig.GitlabToken != "" {
+		loggedConfig.GitlabToken = "***"
+	}
+	if loggedConfig.GiteaToken != "" {
+		loggedConfig.GiteaToken = "***"
+	}
+	slog.Debug("Startup", "config", loggedConfig)
 
+	switch {
+	case command == "diff":
+		err := pkg.MopherDiff(ctx, config, diffFiles[0], diffFiles[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	case command == "history":
+		err := pkg.MopherHistory(ctx, config)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case command == "usage":
+		err := pkg.MopherUsage(ctx, config)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case cron != "":
 		err := pkg.CronMopher(ctx, cron, config)
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		shutdown := make(chan os.Signal, 1)
-		signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
-		sig := <-shutdown
-		log.Println("received shutdown signal", sig)
-		cancel()
-	} else {
-		err := pkg.Mopher(config)
+		log.Println("received shutdown signal")
+	default:
+		err := pkg.Mopher(ctx, config)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 }
 
-func runUpdateMode(execute bool, internal bool) {
+func runUpdateMode(ctx context.Context, execute bool, internal bool) {
 	file, err := os.ReadFile("go.mod")
 	if err != nil {
 		log.Fatal(err)
@@ -165,7 +307,7 @@ func runUpdateMode(execute bool, internal bool) {
 		log.Fatal(err)
 		return
 	}
-	commands, err := pkg.RunUpdateMode(mod, internal)
+	commands, err := pkg.RunUpdateMode(ctx, mod, internal)
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -182,27 +324,61 @@ func runUpdateMode(execute bool, internal bool) {
 	}
 }
 
-func getParamsFromArg(arg string) (org string, dep string, err error) {
+type scanParams struct {
+	Forge   string
+	BaseUrl string //base url of the forge instance, only set for gitlab and gitea
+	Org     string
+	Dep     string
+}
+
+func getParamsFromArg(arg string, forge string) (params scanParams, err error) {
+	scheme := "https://"
+	if strings.HasPrefix(arg, "http://") {
+		scheme = "http://"
+	}
 	arg = strings.TrimPrefix(arg, "http://")
 	arg = strings.TrimPrefix(arg, "https://")
-	if strings.HasPrefix(arg, pkg.GithubUrl) {
+	host := strings.Split(arg, "/")[0]
+	switch {
+	case strings.HasPrefix(arg, pkg.GithubUrl):
 		return getParamsFromGithubUrl(arg)
-	} else {
-		return getParamsFromDir(arg)
+	case getForgeOfHost(host, forge) == pkg.ForgeGitlab:
+		return getParamsFromGitlabUrl(scheme, arg)
+	case getForgeOfHost(host, forge) == pkg.ForgeGitea:
+		return getParamsFromGiteaUrl(scheme, arg)
+	default:
+		return getParamsFromDir(arg, forge)
 	}
 }
 
-func getParamsFromGithubUrl(arg string) (org string, dep string, err error) {
+// getForgeOfHost returns the explicitly selected forge or derives it from the host prefix (e.g. gitlab.example.com)
+func getForgeOfHost(host string, forge string) string {
+	switch {
+	case forge == pkg.ForgeForgejo:
+		return pkg.ForgeGitea
+	case forge != "":
+		return forge
+	case strings.HasPrefix(host, "gitlab."):
+		return pkg.ForgeGitlab
+	case strings.HasPrefix(host, "gitea."), strings.HasPrefix(host, "forgejo."), host == "codeberg.org":
+		return pkg.ForgeGitea
+	default:
+		return ""
+	}
+}
+
+func getParamsFromGithubUrl(arg string) (params scanParams, err error) {
+	params.Forge = pkg.ForgeGithub
 	parts := strings.Split(strings.TrimPrefix(arg, pkg.GithubUrl+"/"), "/")
 	switch len(parts) {
 	case 0:
 		err = errors.New("missing org in github url")
 		return
 	case 1:
-		org = parts[0]
+		params.Org = parts[0]
 		return
 	case 2:
-		org, dep = parts[0], arg
+		params.Org, params.Dep = parts[0], arg
 		return
 	default:
 		err = errors.New("unable to parse github url (path is to long)")
@@ -210,16 +386,61 @@ func getParamsFromGithubUrl(arg string) (org string, dep string, err error) {
 	}
 }
 
-func getParamsFromDir(arg string) (org string, dep string, err error) {
+// getParamsFromGitlabUrl interprets the whole path as group, because gitlab groups may be nested
+func getParamsFromGitlabUrl(scheme string, arg string) (params scanParams, err error) {
+	host, group, _ := strings.Cut(strings.Trim(arg, "/"), "/")
+	if group == "" {
+		return params, errors.New("missing group in gitlab url")
+	}
+	return scanParams{
+		Forge:   pkg.ForgeGitlab,
+		BaseUrl: scheme + host,
+		Org:     group,
+	}, nil
+}
+
+func getParamsFromGiteaUrl(scheme string, arg string) (params scanParams, err error) {
+	host, rest, _ := strings.Cut(strings.Trim(arg, "/"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "":
+		err = errors.New("missing org in gitea url")
+	case len(parts) == 1:
+		params.Org = parts[0]
+	case len(parts) == 2:
+		params.Org, params.Dep = parts[0], arg
+	default:
+		err = errors.New("unable to parse gitea url (path is to long)")
+	}
+	params.Forge = pkg.ForgeGitea
+	params.BaseUrl = scheme + host
+	return params, err
+}
+
+func getParamsFromDir(arg string, forge string) (params scanParams, err error) {
 	file, err := os.ReadFile(path.Join(arg, "go.mod"))
 	if err != nil {
-		return org, dep, err
+		return params, err
 	}
 	mod, err := modfile.ParseLax("go.mod", file, nil)
 	if err != nil {
-		return org, dep, err
+		return params, err
+	}
+	modulePath := mod.Module.Mod.Path
+	host := strings.Split(modulePath, "/")[0]
+	if strings.HasPrefix(modulePath, pkg.GithubUrl) {
+		return getParamsFromGithubUrl(modulePath)
+	}
+	switch getForgeOfHost(host, forge) {
+	case pkg.ForgeGitlab:
+		params, err = getParamsFromGitlabUrl("https://", path.Dir(modulePath))
+		params.Dep = modulePath
+		return params, err
+	case pkg.ForgeGitea:
+		return getParamsFromGiteaUrl("https://", modulePath)
+	default:
+		return getParamsFromGithubUrl(modulePath)
 	}
-	return getParamsFromGithubUrl(mod.Module.Mod.Path)
 }
 
 var camel = regexp.MustCompile("(^[^A-Z]*|[A-Z]*)([A-Z][^A-Z]+|$)")
@@ -18,3 +18,5 @@ package pkg
 
 const GithubUrl = "github.com"
 const GithubRawUrl = "https://raw.githubusercontent.com/"
+const GithubApiHost = "api.github.com"
+const DockerhubGolangTagsUrl = "https://hub.docker.com/v2/repositories/library/golang/tags"
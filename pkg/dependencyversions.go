@@ -17,13 +17,12 @@
 package pkg
 
 import (
-	"fmt"
 	"slices"
 	"sort"
 	"strings"
 )
 
-func (this *Parsed) PrintDependencyVersionWarnings() (deprecated []string, err error) {
+func (this *Parsed) GetDependencyVersionFindings() (result []Finding) {
 	//make result deterministic by sorting the keys
 	keys := []string{}
 	for key, _ := range this.Latest {
@@ -32,58 +31,37 @@ func (this *Parsed) PrintDependencyVersionWarnings() (deprecated []string, err e
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		subDependent, err := this.PrintVersionWarningsForDependency(key)
-		if err != nil {
-			return deprecated, err
-		}
-		deprecated = append(deprecated, subDependent...)
+		result = append(result, this.GetVersionFindingsForDependency(key)...)
 	}
-
-	return deprecated, nil
+	return result
 }
 
-func (this *Parsed) PrintVersionWarningsForDependency(dep string) (dependent []string, err error) {
+func (this *Parsed) GetVersionFindingsForDependency(dep string) (result []Finding) {
 	latestVersion := this.Latest[dep]
-	list, err := this.listOldDependencyVersionUsage(dep, latestVersion)
-	if err != nil {
-		return dependent, err
-	}
-	if len(list) == 0 {
-		return dependent, nil
-	}
-	_, err = fmt.Fprintf(this.output, "\n\nthe following repositories use a %v version != %v %v\n", dep, latestVersion.MainHash, latestVersion.LatestTag)
-	if err != nil {
-		return dependent, err
-	}
-	slices.SortFunc(list, func(a, b VersionUsageRef) int {
-		result := strings.Compare(a.Version, b.Version)
+	list := this.listOldDependencyVersionUsage(dep, latestVersion)
+	slices.SortFunc(list, func(a, b Finding) int {
+		result := compareVersions(a.UsedVersion, b.UsedVersion)
 		if result == 0 {
-			result = strings.Compare(a.Name, b.Name)
+			result = strings.Compare(a.Module, b.Module)
 		}
 		return result
 	})
-	for _, e := range list {
-		_, err = fmt.Fprintln(this.output, e.Version, e.Name)
-		if err != nil {
-			return dependent, err
-		}
-		dependent = append(dependent, e.Name)
-	}
-	return dependent, nil
+	return list
 }
 
-func (this *Parsed) listOldDependencyVersionUsage(dep string, version LatestCommitInfo) (result []VersionUsageRef, err error) {
+func (this *Parsed) listOldDependencyVersionUsage(dep string, version LatestCommitInfo) (result []Finding) {
 	for _, ref := range this.Inverse[dep] {
-		versionStr := version.MainHash
-		if ref.SemanticVersion {
-			versionStr = version.LatestTag
-		}
-		if ref.UsesVersion != versionStr {
-			result = append(result, VersionUsageRef{
-				Name:    ref.UserModule,
-				Version: ref.UsesVersion,
+		versionStr := getExpectedDependencyVersion(ref.SemanticVersion, version)
+		if isDependencyVersionOutdated(ref.RawVersion, version) {
+			result = append(result, Finding{
+				Kind:            FindingOutdatedDependency,
+				Module:          ref.UserModule,
+				Dependency:      dep,
+				UsedVersion:     ref.UsesVersion,
+				ExpectedVersion: versionStr,
+				Lag:             getDependencyLag(ref.RawVersion, version),
 			})
 		}
 	}
-	return result, nil
+	return result
 }
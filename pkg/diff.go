@@ -0,0 +1,318 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"io"
+	"io/fs"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Diff describes the changes between two scans of the same org
+type Diff struct {
+	Org                 string             `json:"org"`
+	NewRepos            []string           `json:"new_repos"`
+	RemovedRepos        []string           `json:"removed_repos"`
+	NewTags             []TagChange        `json:"new_tags"`             //org modules with a new latest tag
+	UpdatedDependencies []DependencyChange `json:"updated_dependencies"` //org dependencies with a changed version in go.mod
+	FellBehind          []DependencyChange `json:"fell_behind"`          //unchanged org dependencies that are outdated since the new scan
+	NewFindings         []Finding          `json:"new_findings"`
+	ResolvedFindings    []Finding          `json:"resolved_findings"`
+}
+
+type TagChange struct {
+	Module string `json:"module"`
+	OldTag string `json:"old_tag"`
+	NewTag string `json:"new_tag"`
+}
+
+type DependencyChange struct {
+	Module     string `json:"module"`
+	Dependency string `json:"dependency"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+	Lag        Lag    `json:"lag"` //lag of NewVersion
+}
+
+// GetDiff compares the previous with the current scan; findings are compared by Finding.Key and computed with the same options for both scans
+func GetDiff(previous *Parsed, current *Parsed, options ReportOptions) (diff Diff, err error) {
+	diff = Diff{
+		Org:                 current.org,
+		NewRepos:            []string{},
+		RemovedRepos:        []string{},
+		NewTags:             []TagChange{},
+		UpdatedDependencies: []DependencyChange{},
+		FellBehind:          []DependencyChange{},
+	}
+	oldRepos := map[string]bool{}
+	for _, repo := range previous.Repos {
+		oldRepos[repo.FullName] = true
+	}
+	newRepos := map[string]bool{}
+	for _, repo := range current.Repos {
+		newRepos[repo.FullName] = true
+		if !oldRepos[repo.FullName] {
+			diff.NewRepos = append(diff.NewRepos, repo.FullName)
+		}
+	}
+	for _, repo := range previous.Repos {
+		if !newRepos[repo.FullName] {
+			diff.RemovedRepos = append(diff.RemovedRepos, repo.FullName)
+		}
+	}
+	sort.Strings(diff.NewRepos)
+	sort.Strings(diff.RemovedRepos)
+
+	for _, name := range getSortedKeys(current.Latest) {
+		oldLatest, ok := previous.Latest[name]
+		newTag := current.Latest[name].LatestTag
+		if ok && newTag != "" && oldLatest.LatestTag != newTag {
+			diff.NewTags = append(diff.NewTags, TagChange{Module: name, OldTag: oldLatest.LatestTag, NewTag: newTag})
+		}
+	}
+
+	for _, name := range getSortedKeys(current.Modules) {
+		oldModule, ok := previous.Modules[name]
+		if !ok {
+			continue
+		}
+		for _, req := range current.Modules[name].Require {
+			latest, isOrgModule := current.Latest[req.Mod.Path]
+			if !isOrgModule {
+				continue
+			}
+			index := slices.IndexFunc(oldModule.Require, func(oldReq *modfile.Require) bool {
+				return oldReq.Mod.Path == req.Mod.Path
+			})
+			if index < 0 {
+				continue
+			}
+			change := DependencyChange{
+				Module:     name,
+				Dependency: req.Mod.Path,
+				OldVersion: oldModule.Require[index].Mod.Version,
+				NewVersion: req.Mod.Version,
+				Lag:        getDependencyLag(req.Mod.Version, latest),
+			}
+			switch {
+			case change.OldVersion != change.NewVersion:
+				diff.UpdatedDependencies = append(diff.UpdatedDependencies, change)
+			case isDependencyVersionOutdated(req.Mod.Version, latest) && !isDependencyVersionOutdated(req.Mod.Version, previous.Latest[req.Mod.Path]):
+				diff.FellBehind = append(diff.FellBehind, change)
+			}
+		}
+	}
+
+	previousReport, err := previous.GetReport(options)
+	if err != nil {
+		return diff, err
+	}
+	currentReport, err := current.GetReport(options)
+	if err != nil {
+		return diff, err
+	}
+	diff.NewFindings, diff.ResolvedFindings = compareFindings(previousReport.Findings, currentReport.Findings)
+	return diff, nil
+}
+
+// compareFindings returns the findings of current that are not in previous and the findings of previous that are not in current
+func compareFindings(previous []Finding, current []Finding) (added []Finding, resolved []Finding) {
+	added, resolved = []Finding{}, []Finding{}
+	previousKeys := map[string]bool{}
+	for _, f := range previous {
+		previousKeys[f.Key()] = true
+	}
+	currentKeys := map[string]bool{}
+	for _, f := range current {
+		currentKeys[f.Key()] = true
+		if !previousKeys[f.Key()] {
+			added = append(added, f)
+		}
+	}
+	for _, f := range previous {
+		if !currentKeys[f.Key()] {
+			resolved = append(resolved, f)
+		}
+	}
+	return added, resolved
+}
+
+func getSortedKeys[T any](m map[string]T) (result []string) {
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (this Diff) IsEmpty() bool {
+	return len(this.NewRepos) == 0 && len(this.RemovedRepos) == 0 && len(this.NewTags) == 0 &&
+		len(this.UpdatedDependencies) == 0 && len(this.FellBehind) == 0 &&
+		len(this.NewFindings) == 0 && len(this.ResolvedFindings) == 0
+}
+
+func EncodeDiff(diff Diff, encoding string) (string, error) {
+	switch encoding {
+	case EncodingJsonData:
+		temp, err := json.Marshal(diff)
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingJson:
+		text, err := EncodeDiff(diff, EncodingText)
+		if err != nil {
+			return "", err
+		}
+		temp, err := json.Marshal(strings.TrimSpace(text))
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingText:
+		buf := strings.Builder{}
+		err := WriteTextDiff(&buf, diff)
+		return buf.String(), err
+	default:
+		return "", fmt.Errorf("unexpected output encoding for diffs: %v", encoding)
+	}
+}
+
+func WriteTextDiff(out io.Writer, diff Diff) (err error) {
+	sections := []struct {
+		title string
+		lines []string
+	}{
+		{title: "new repositories:", lines: diff.NewRepos},
+		{title: "removed repositories:", lines: diff.RemovedRepos},
+		{title: "new releases:", lines: mapList(diff.NewTags, func(e TagChange) string {
+			return fmt.Sprintf("%v %v -> %v", e.Module, e.OldTag, e.NewTag)
+		})},
+		{title: "updated dependencies:", lines: mapList(diff.UpdatedDependencies, func(e DependencyChange) string {
+			return fmt.Sprintf("%v %v %v -> %v (%v)", e.Module, e.Dependency, e.OldVersion, e.NewVersion, e.Lag)
+		})},
+		{title: "dependencies that fell behind:", lines: mapList(diff.FellBehind, func(e DependencyChange) string {
+			return fmt.Sprintf("%v %v %v (%v)", e.Module, e.Dependency, e.NewVersion, e.Lag)
+		})},
+		{title: "new warnings:", lines: mapList(diff.NewFindings, Finding.String)},
+		{title: "resolved warnings:", lines: mapList(diff.ResolvedFindings, Finding.String)},
+	}
+	if diff.IsEmpty() {
+		_, err = fmt.Fprintf(out, "\n\nno changes in %v\n", diff.Org)
+		return err
+	}
+	for _, section := range sections {
+		if len(section.lines) == 0 {
+			continue
+		}
+		_, err = fmt.Fprintln(out, "\n\n"+section.title)
+		if err != nil {
+			return err
+		}
+		for _, line := range section.lines {
+			_, err = fmt.Fprintln(out, line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func mapList[T any](list []T, f func(T) string) (result []string) {
+	for _, e := range list {
+		result = append(result, f(e))
+	}
+	return result
+}
+
+// isDiffEncoding checks if EncodeDiff supports the encoding
+func isDiffEncoding(encoding string) bool {
+	return encoding == EncodingText || encoding == EncodingJson || encoding == EncodingJsonData
+}
+
+// MopherDiff writes the Diff between two Snapshot files to the configured output
+func MopherDiff(ctx context.Context, config MopherConfig, oldSnapshotFile string, newSnapshotFile string) error {
+	if !isDiffEncoding(config.OutputEncode) {
+		return fmt.Errorf("unexpected output encoding for diffs: %v", config.OutputEncode)
+	}
+	tmpl, err := template.New("templ").Parse(config.OutputTemplate)
+	if err != nil {
+		return err
+	}
+	previous, err := LoadSnapshotFile(oldSnapshotFile)
+	if err != nil {
+		return err
+	}
+	current, err := LoadSnapshotFile(newSnapshotFile)
+	if err != nil {
+		return err
+	}
+	diff, err := GetDiff(previous, current, config.getReportOptions())
+	if err != nil {
+		return err
+	}
+	output, err := EncodeDiff(diff, config.OutputEncode)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, config, tmpl, output, map[string]interface{}{"Diff": diff})
+}
+
+// loadDiffBase returns nil if the file does not exist yet
+func loadDiffBase(file string) (*Parsed, error) {
+	parsed, err := LoadSnapshotFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return parsed, err
+}
+
+// outputDiff writes the Diff between previous and current, if something changed, and stores current as the next diff base.
+// the first run (previous == nil) only stores the diff base.
+// repositories that could not be checked keep their modules of the previous run
+func outputDiff(ctx context.Context, config MopherConfig, tmpl *template.Template, previous *Parsed, current *Parsed) error {
+	if previous != nil {
+		current.addModulesOfFailedRepos(previous)
+		diff, err := GetDiff(previous, current, config.getReportOptions())
+		if err != nil {
+			return err
+		}
+		if !diff.IsEmpty() {
+			output, err := EncodeDiff(diff, config.OutputEncode)
+			if err != nil {
+				return err
+			}
+			err = writeOutput(ctx, config, tmpl, output, map[string]interface{}{"Diff": diff})
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		log.Println("no previous scan in", config.DiffFile, "changes are reported starting with the next run")
+	}
+	return WriteSnapshotFile(config.DiffFile, current.GetSnapshot())
+}
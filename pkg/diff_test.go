@@ -0,0 +1,103 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffFile(t *testing.T) {
+	diffFile := filepath.Join(t.TempDir(), "diff.json")
+	a := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.3"))
+	run := func(repos ...testenv.Repo) string {
+		t.Helper()
+		config := testConfig(startOrg(t, repos...))
+		config.DiffFile = diffFile
+		return runMopher(t, config)
+	}
+
+	if output := run(libRepo(), a); output != "" {
+		t.Errorf("first run should only store the diff base, got:\n%v", output)
+	}
+	if readTestFile(t, diffFile) == "" {
+		t.Fatal("diff base was not stored")
+	}
+	if output := run(libRepo(), a); output != "" {
+		t.Errorf("unexpected diff without changes:\n%v", output)
+	}
+
+	lib := withRelease(libRepo(), "v1.4.0")
+	output := run(lib, a)
+	for _, expected := range []string{
+		"new releases:\ngithub.com/org/lib v1.2.3 -> v1.4.0",
+		"dependencies that fell behind:\ngithub.com/org/a github.com/org/lib v1.2.3",
+		"new warnings:",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("missing %q in diff:\n%v", expected, output)
+		}
+	}
+
+	// repositories that can not be checked keep their modules, findings and releases of the previous run
+	for _, repos := range [][]testenv.Repo{{lib, unavailable(a)}, {lib, a}, {unavailable(lib), a}, {lib, a}} {
+		if output := run(repos...); output != "" {
+			t.Errorf("unexpected diff after repository error:\n%v", output)
+		}
+	}
+
+	// listing errors keep the diff base
+	base := readTestFile(t, diffFile)
+	config := testConfig(startOrg(t, lib, a))
+	config.DiffFile = diffFile
+	config.Org = "unknown"
+	if err := Mopher(context.Background(), config); err == nil {
+		t.Error("expected listing error")
+	}
+	if readTestFile(t, diffFile) != base {
+		t.Error("diff base changed after listing error")
+	}
+}
+
+func TestDiffEncodings(t *testing.T) {
+	diff := Diff{Org: "org", NewRepos: []string{"a"}}
+	for _, encoding := range []string{EncodingText, EncodingJson, EncodingJsonData} {
+		if _, err := EncodeDiff(diff, encoding); err != nil {
+			t.Errorf("unexpected error for %v: %v", encoding, err)
+		}
+	}
+	for _, encoding := range []string{EncodingMarkdown, EncodingHtml, EncodingSarif, EncodingJunit, "unknown"} {
+		if _, err := EncodeDiff(diff, encoding); err == nil {
+			t.Errorf("expected error for %v", encoding)
+		}
+	}
+
+	// unsupported encodings are rejected before the scan
+	env := startOrg(t, libRepo())
+	config := testConfig(env)
+	config.DiffFile = filepath.Join(t.TempDir(), "diff.json")
+	config.OutputEncode = EncodingMarkdown
+	if err := Mopher(context.Background(), config); err == nil {
+		t.Error("expected error for markdown diff")
+	}
+	if requests := env.Requests("/"); requests != 0 {
+		t.Errorf("expected no scan, got %v requests", requests)
+	}
+}
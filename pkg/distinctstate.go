@@ -0,0 +1,103 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"text/template"
+	"time"
+)
+
+// distinctState is persisted between runs of the per-finding distinct mode (see MopherConfig.DistinctStateFile)
+type distinctState struct {
+	Findings    []Finding         `json:"findings"`
+	ModuleRepos map[string]string `json:"module_repos"` //repository of every org module, used to keep the findings of repositories that could not be checked
+	LastDigest  time.Time         `json:"last_digest"`
+}
+
+func readDistinctState(file string) (state distinctState, err error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(content, &state)
+	return state, err
+}
+
+func writeDistinctState(file string, state distinctState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, content)
+}
+
+// outputFindingChanges writes the findings that are new or resolved since the previous run.
+// if config.DigestInterval has passed since the last digest, the full report is written instead.
+// nothing is written if no finding changed. the state is only updated after a successful write
+func outputFindingChanges(ctx context.Context, config MopherConfig, tmpl *template.Template, report Report) error {
+	state, err := readDistinctState(config.DistinctStateFile)
+	if err != nil {
+		return err
+	}
+	findings, moduleRepos := keepFindingsOfFailedRepos(state, report)
+	diff := Diff{Org: report.Org}
+	diff.NewFindings, diff.ResolvedFindings = compareFindings(state.Findings, findings)
+	now := time.Now()
+	switch {
+	case config.DigestInterval > 0 && now.Sub(state.LastDigest) >= config.DigestInterval:
+		output, err := EncodeReport(report, config.OutputEncode)
+		if err != nil {
+			return err
+		}
+		err = writeOutput(ctx, config, tmpl, output, map[string]interface{}{"Report": report, "Diff": diff})
+		if err != nil {
+			return err
+		}
+		state.LastDigest = now
+	case len(diff.NewFindings) > 0 || len(diff.ResolvedFindings) > 0:
+		output, err := EncodeDiff(diff, config.OutputEncode)
+		if err != nil {
+			return err
+		}
+		err = writeOutput(ctx, config, tmpl, output, map[string]interface{}{"Report": report, "Diff": diff})
+		if err != nil {
+			return err
+		}
+	}
+	state.Findings = findings
+	state.ModuleRepos = moduleRepos
+	return writeDistinctState(config.DistinctStateFile, state)
+}
+
+// keepFindingsOfFailedRepos adds the previous findings of modules and dependencies that are missing in the report,
+// because their repository could not be checked (see getModulesOfFailedRepos)
+func keepFindingsOfFailedRepos(state distinctState, report Report) (findings []Finding, moduleRepos map[string]string) {
+	moduleRepos = getModuleRepos(report.Sources)
+	kept := getModulesOfFailedRepos(state.ModuleRepos, moduleRepos, getErrorRepos(report.Errors))
+	for name, repo := range kept {
+		moduleRepos[name] = repo
+	}
+	return keepFindingsOfModules(report.Findings, state.Findings, kept), moduleRepos
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDistinctStateFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "distinct.json")
+	a := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0"))
+	run := func(repos ...testenv.Repo) string {
+		t.Helper()
+		config := testConfig(startOrg(t, repos...))
+		config.DistinctStateFile = stateFile
+		return runMopher(t, config)
+	}
+
+	finding := "github.com/org/a uses github.com/org/lib v1.2.0 != v1.2.3 (behind-patch)"
+	output := run(libRepo(), a)
+	if !strings.Contains(output, "new warnings:\n"+finding) {
+		t.Errorf("missing new finding in:\n%v", output)
+	}
+	if output = run(libRepo(), a); output != "" {
+		t.Errorf("unexpected output without changes:\n%v", output)
+	}
+
+	// neither a failed module repository nor a failed dependency repository resolves the finding
+	for _, repos := range [][]testenv.Repo{{libRepo(), unavailable(a)}, {libRepo(), a}, {unavailable(libRepo()), a}, {libRepo(), a}} {
+		if output = run(repos...); output != "" {
+			t.Errorf("unexpected output after repository error:\n%v", output)
+		}
+	}
+
+	updated := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.3"))
+	output = run(libRepo(), updated)
+	if !strings.Contains(output, "resolved warnings:\n"+finding) {
+		t.Errorf("missing resolved finding in:\n%v", output)
+	}
+	if strings.Contains(output, "new warnings:") {
+		t.Errorf("unexpected new finding in:\n%v", output)
+	}
+}
@@ -17,6 +17,7 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -31,11 +32,15 @@ type TagStruct struct {
 	Name string `json:"name"`
 }
 
-func getGolangTagsFromDockerhub() (tags []string, err error) {
-	resp, err := http.Get("https://hub.docker.com/v2/repositories/library/golang/tags")
+func getGolangTagsFromDockerhub(ctx context.Context, client *http.Client, tagsUrl string) (tags []string, err error) {
+	if tagsUrl == "" {
+		tagsUrl = DockerhubGolangTagsUrl
+	}
+	resp, err := httpGet(ctx, client, tagsUrl)
 	if err != nil {
 		return tags, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		payload, _ := io.ReadAll(resp.Body)
 		return tags, fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	EncodingText     = "plain/text"
+	EncodingJson     = "application/json"            //plain text report as json string, usable in templates like '{"text": {{.Output}}}'
+	EncodingJsonData = "application/vnd.mopher+json" //structured report
+	EncodingMarkdown = "text/markdown"
+	EncodingHtml     = "text/html" //self-contained dashboard
+	EncodingSarif    = "application/sarif+json"
+	EncodingJunit    = "application/junit+xml"
+)
+
+func EncodeReport(report Report, encoding string) (string, error) {
+	switch encoding {
+	case EncodingJsonData:
+		temp, err := json.Marshal(report)
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingJson:
+		text, err := EncodeReport(report, EncodingText)
+		if err != nil {
+			return "", err
+		}
+		temp, err := json.Marshal(strings.TrimSpace(text))
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingMarkdown:
+		buf := strings.Builder{}
+		err := WriteMarkdownReport(&buf, report)
+		return buf.String(), err
+	case EncodingHtml:
+		buf := strings.Builder{}
+		err := WriteHtmlReport(&buf, report)
+		return buf.String(), err
+	case EncodingSarif:
+		buf := strings.Builder{}
+		err := WriteSarifReport(&buf, report)
+		return buf.String(), err
+	case EncodingJunit:
+		buf := strings.Builder{}
+		err := WriteJunitReport(&buf, report)
+		return buf.String(), err
+	case EncodingText:
+		fallthrough
+	default:
+		buf := strings.Builder{}
+		err := WriteTextReport(&buf, report)
+		return buf.String(), err
+	}
+}
+
+func WriteTextReport(out io.Writer, report Report) (err error) {
+	if report.Dependency != "" {
+		err = writeTextDependents(out, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingWrongModuleName); len(list) > 0 {
+		_, err = fmt.Fprintln(out, "\n\nfound unexpected module names:")
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			_, err = fmt.Fprintln(out, f.Module)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingOutdatedGoVersion); len(list) > 0 {
+		_, err = fmt.Fprintln(out, "\n\nthe following repositories use a go version !=", list[0].ExpectedVersion)
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			_, err = fmt.Fprintln(out, f.UsedVersion, f.Module)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingUnsyncedDevBranch); len(list) > 0 {
+		_, err = fmt.Fprintln(out, "\n\nfound repositories where master/main and dev branches are not synced:")
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			_, err = fmt.Fprintln(out, f.Module)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	currentDep := ""
+	for _, f := range report.FindingsOfKind(FindingOutdatedDependency) {
+		if f.Dependency != currentDep {
+			currentDep = f.Dependency
+			latest := report.Latest[currentDep]
+			_, err = fmt.Fprintf(out, "\n\nthe following repositories use a %v version != %v %v\n", currentDep, latest.MainHash, latest.LatestTag)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(out, "%v %v (%v)\n", f.UsedVersion, f.Module, f.Lag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		_, err = fmt.Fprintln(out, "\n\nthe following repositories could not be checked:")
+		if err != nil {
+			return err
+		}
+		for _, e := range report.Errors {
+			_, err = fmt.Fprintln(out, e.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "\n\nrecommended update order:\n")
+	if err != nil {
+		return err
+	}
+	for _, e := range report.UpdateOrder {
+		_, err = fmt.Fprintln(out, e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTextDependents(out io.Writer, report Report) (err error) {
+	if len(report.Dependents) == 0 {
+		_, err = fmt.Fprintf(out, "\n\n%v is used by no %v repository as dependency\n", report.Dependency, report.Org)
+		return err
+	}
+	_, err = fmt.Fprintf(out, "\n\n%v is used by the following repositories (sorted by usage-version)\n", report.Dependency)
+	if err != nil {
+		return err
+	}
+	for _, ref := range report.Dependents {
+		_, err = fmt.Fprintln(out, ref.Module, ref.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJsonEncodings(t *testing.T) {
+	env := startEncodingTestEnv(t)
+	text := runMopher(t, testConfig(env))
+
+	// application/json keeps the text report usable in slack templates
+	config := testConfig(env)
+	config.OutputTemplate = `{"text": {{.Output}}}`
+	config.OutputEncode = EncodingJson
+	message := struct {
+		Text string `json:"text"`
+	}{}
+	err := json.Unmarshal([]byte(runMopher(t, config)), &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != strings.TrimSpace(text) {
+		t.Errorf("expected text report\n%v\ngot\n%v", text, message.Text)
+	}
+
+	config.OutputTemplate = "{{.Output}}"
+	config.OutputEncode = EncodingJsonData
+	report := Report{}
+	err = json.Unmarshal([]byte(runMopher(t, config)), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Findings) == 0 || len(report.UpdateOrder) == 0 {
+		t.Errorf("expected findings and update order in structured report, got %#v", report)
+	}
+}
@@ -17,41 +17,93 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
-	"github.com/google/go-github/v54/github"
+	"golang.org/x/mod/module"
 	"golang.org/x/mod/semver"
 	"log/slog"
+	"strings"
+	"time"
 )
 
-func getLatestInfo(repo *github.Repository) (result LatestCommitInfo, err error) {
-	return getLatestInfoFromUrl(repo.GetCloneURL())
-}
-
 var LatestInfoError = errors.New("LatestInfoError")
 
-func getLatestInfoFromUrl(remoteUrl string) (result LatestCommitInfo, err error) {
+// listRemoteRefs runs a 'git ls-remote'. a timeout of 0 uses the default of 30 seconds
+func listRemoteRefs(ctx context.Context, remoteUrl string, auth transport.AuthMethod, timeout time.Duration) (refs []*plumbing.Reference, err error) {
 	slog.Debug("git ls-remote " + remoteUrl)
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
 		Name: "origin",
 		URLs: []string{remoteUrl},
 	})
-	refs, err := rem.List(&git.ListOptions{
-		Timeout: 30,
+	refs, err = rem.ListContext(ctx, &git.ListOptions{
+		Auth:    auth,
+		Timeout: int(timeout.Seconds()),
 	})
 	if err != nil {
-		return result, fmt.Errorf("%w: remoteUrl=%v", errors.Join(err, LatestInfoError), remoteUrl)
+		return refs, fmt.Errorf("%w: remoteUrl=%v", errors.Join(err, LatestInfoError), remoteUrl)
 	}
+	return refs, nil
+}
+
+// TagSelection decides which tags are candidates for LatestCommitInfo.LatestTag
+type TagSelection struct {
+	Prefix            string //tags must start with this prefix, which is stripped (e.g. "api/" for the module in api/go.mod)
+	Major             string //major version of the module path (e.g. "v2" for example.com/foo/v2); empty for v0/v1 modules
+	IncludePrerelease bool
+}
+
+func newTagSelection(modulePath string, modFilePath string, includePrerelease bool) TagSelection {
+	_, pathMajor, _ := module.SplitPathVersion(modulePath)
+	return TagSelection{
+		Prefix:            getModuleTagPrefix(modFilePath, pathMajor),
+		Major:             module.PathMajorPrefix(pathMajor),
+		IncludePrerelease: includePrerelease,
+	}
+}
 
+// accepts returns the version of the tag (without prefix) and true if the tag is a candidate for the latest tag
+func (this TagSelection) accepts(tag string) (version string, ok bool) {
+	version, ok = strings.CutPrefix(tag, this.Prefix)
+	if !ok || strings.Contains(version, "/") {
+		return version, false
+	}
+	if !semver.IsValid(version) || semver.Build(version) != "" {
+		slog.Debug("ignored invalid tag", "tag", tag)
+		return version, false
+	}
+	if semver.Prerelease(version) != "" && !this.IncludePrerelease {
+		slog.Debug("ignored pre-release tag", "tag", tag)
+		return version, false
+	}
+	major := semver.Major(version)
+	if this.Major == "" {
+		return version, major == "v0" || major == "v1"
+	}
+	return version, major == this.Major
+}
+
+func getLatestInfoFromRefs(refs []*plumbing.Reference, selection TagSelection) (result LatestCommitInfo, err error) {
 	defaultRefName := plumbing.Master
 	//recheck head, in case 'main' branch is used
 	for _, ref := range refs {
 		if ref.Name() == plumbing.HEAD {
-			defaultRefName = ref.Target()
+			if ref.Type() == plumbing.SymbolicReference {
+				defaultRefName = ref.Target()
+			} else {
+				//detached HEAD or server without symref capability
+				defaultRefName = plumbing.HEAD
+			}
 			break
 		}
 	}
@@ -59,8 +111,11 @@ func getLatestInfoFromUrl(remoteUrl string) (result LatestCommitInfo, err error)
 	latestTag := ""
 	for _, ref := range refs {
 		if ref.Name().IsTag() {
-			refVersion := ref.Name().Short()
-			if latestTag == "" || semver.Compare(latestTag, refVersion) < 1 {
+			refVersion, ok := selection.accepts(ref.Name().Short())
+			if !ok {
+				continue
+			}
+			if latestTag == "" || semver.Compare(latestTag, refVersion) < 0 {
 				latestTag = refVersion
 			}
 		}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"testing"
+)
+
+func TestGetLatestInfoFromRefs(t *testing.T) {
+	tags := []string{
+		"v1.0.0", "v1.2.0", "v1.10.0", "v1.9.0", "v1.11.0-rc.1", "v1.12.0+build", "release-2023", "latest", "1.13.0",
+		"v2.0.0-beta", "v2.0.0", "v2.1.0-rc.1",
+		"api/v1.3.0", "api/v1.4.0-rc.1", "api/v2.0.0",
+	}
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+		plumbing.NewHashReference("refs/heads/main", plumbing.NewHash("1111111111111111111111111111111111111111")),
+		plumbing.NewHashReference("refs/heads/dev", plumbing.NewHash("2222222222222222222222222222222222222222")),
+	}
+	for _, tag := range tags {
+		refs = append(refs, plumbing.NewHashReference(plumbing.NewTagReferenceName(tag), plumbing.NewHash("3333333333333333333333333333333333333333")))
+	}
+	cases := []struct {
+		name              string
+		modulePath        string
+		modFilePath       string
+		includePrerelease bool
+		expected          string
+	}{
+		{name: "v1", modulePath: "example.com/lib", modFilePath: "go.mod", expected: "v1.10.0"},
+		{name: "v1 with pre-releases", modulePath: "example.com/lib", modFilePath: "go.mod", includePrerelease: true, expected: "v1.11.0-rc.1"},
+		{name: "v2", modulePath: "example.com/lib/v2", modFilePath: "go.mod", expected: "v2.0.0"},
+		{name: "v2 with pre-releases", modulePath: "example.com/lib/v2", modFilePath: "go.mod", includePrerelease: true, expected: "v2.1.0-rc.1"},
+		{name: "v2 in major subdirectory", modulePath: "example.com/lib/v2", modFilePath: "v2/go.mod", expected: "v2.0.0"},
+		{name: "v3 without tags", modulePath: "example.com/lib/v3", modFilePath: "go.mod", expected: ""},
+		{name: "submodule", modulePath: "example.com/lib/api", modFilePath: "api/go.mod", expected: "v1.3.0"},
+		{name: "submodule with pre-releases", modulePath: "example.com/lib/api", modFilePath: "api/go.mod", includePrerelease: true, expected: "v1.4.0-rc.1"},
+		{name: "v2 submodule", modulePath: "example.com/lib/api/v2", modFilePath: "api/v2/go.mod", expected: "v2.0.0"},
+		{name: "submodule without tags", modulePath: "example.com/lib/cmd", modFilePath: "cmd/go.mod", expected: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := getLatestInfoFromRefs(refs, newTagSelection(c.modulePath, c.modFilePath, c.includePrerelease))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := LatestCommitInfo{LatestTag: c.expected, MainHash: "111111111111", DevHash: "222222222222"}
+			if result != expected {
+				t.Errorf("expected %#v, got %#v", expected, result)
+			}
+		})
+	}
+}
+
+func TestGetLatestInfoFromRefsHead(t *testing.T) {
+	main := plumbing.NewHashReference("refs/heads/main", plumbing.NewHash("1111111111111111111111111111111111111111"))
+	master := plumbing.NewHashReference(plumbing.Master, plumbing.NewHash("2222222222222222222222222222222222222222"))
+	detached := plumbing.NewHashReference(plumbing.HEAD, plumbing.NewHash("3333333333333333333333333333333333333333"))
+	cases := []struct {
+		name     string
+		refs     []*plumbing.Reference
+		expected string
+		err      bool
+	}{
+		{name: "symbolic head", refs: []*plumbing.Reference{plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"), main, master}, expected: "111111111111"},
+		{name: "without head", refs: []*plumbing.Reference{main, master}, expected: "222222222222"},
+		{name: "detached head", refs: []*plumbing.Reference{detached, main, master}, expected: "333333333333"},
+		{name: "missing default branch", refs: []*plumbing.Reference{main}, err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := getLatestInfoFromRefs(c.refs, newTagSelection("example.com/lib", "go.mod", false))
+			if (err != nil) != c.err {
+				t.Fatalf("expected error=%v, got %v", c.err, err)
+			}
+			if result.MainHash != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, result.MainHash)
+			}
+		})
+	}
+}
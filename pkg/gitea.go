@@ -0,0 +1,204 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// giteaClient works for gitea and forgejo instances
+type giteaClient struct {
+	http           *http.Client
+	baseUrl        string
+	host           string
+	token          string
+	requestTimeout time.Duration
+}
+
+type giteaRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	CloneUrl      string `json:"clone_url"`
+	HtmlUrl       string `json:"html_url"`
+	Language      string `json:"language"`
+	Archived      bool   `json:"archived"`
+	Empty         bool   `json:"empty"`
+}
+
+// newGiteaClient creates a client for the gitea/forgejo instance at config.GiteaUrl (e.g. https://gitea.example.com).
+// config.GiteaToken is optional and used for api and git requests
+func newGiteaClient(config MopherConfig) (client *giteaClient, err error) {
+	token, baseUrl := config.GiteaToken, config.GiteaUrl
+	if baseUrl == "" {
+		return client, errors.New("missing gitea url")
+	}
+	parsedUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return client, err
+	}
+	authValue := ""
+	if token != "" {
+		authValue = "token " + token
+	}
+	client = &giteaClient{
+		http:           newHttpClient(config, "Authorization", authValue, parsedUrl.Host),
+		baseUrl:        strings.TrimSuffix(baseUrl, "/"),
+		host:           parsedUrl.Host,
+		token:          token,
+		requestTimeout: config.RequestTimeout,
+	}
+	return client, nil
+}
+
+func (this *giteaClient) Host() string {
+	return this.host
+}
+
+func (this *giteaClient) ListRepos(ctx context.Context, org string) (result []Repository, err error) {
+	const limit = 50
+	for page := 1; ; page++ {
+		slog.Debug(fmt.Sprintf("request gitea %v %v", limit, page))
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("page", strconv.Itoa(page))
+		repos := []giteaRepository{}
+		err = this.getJson(ctx, "/api/v1/orgs/"+url.PathEscape(org)+"/repos?"+query.Encode(), &repos)
+		if err != nil {
+			return result, err
+		}
+		for _, repo := range repos {
+			if repo.Empty {
+				slog.Debug("ignored empty repo", "repo-name", repo.FullName)
+				continue
+			}
+			result = append(result, Repository{
+				Name:          repo.Name,
+				FullName:      repo.FullName,
+				DefaultBranch: repo.DefaultBranch,
+				CloneUrl:      repo.CloneUrl,
+				HtmlUrl:       repo.HtmlUrl,
+				Forge:         ForgeGitea,
+				Language:      repo.Language,
+				Archived:      repo.Archived,
+				//gitea computes the language asynchronously and not at all if language statistics are disabled
+				LanguageUnknown: repo.Language == "",
+			})
+		}
+		//the server may limit the page size below limit (MAX_RESPONSE_ITEMS), so only an empty page ends the listing
+		if len(repos) == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+type giteaTree struct {
+	Tree []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	} `json:"tree"`
+	Truncated  bool `json:"truncated"`
+	TotalCount int  `json:"total_count"`
+}
+
+func (this *giteaClient) ListModFiles(ctx context.Context, repo Repository) (result []string, err error) {
+	const limit = 1000
+	ref := repo.DefaultBranch
+	if ref == "" {
+		ref = "HEAD"
+	}
+	count := 0
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("recursive", "true")
+		query.Set("per_page", strconv.Itoa(limit))
+		query.Set("page", strconv.Itoa(page))
+		tree := giteaTree{}
+		err = this.getJson(ctx, "/api/v1/repos/"+repo.FullName+"/git/trees/"+url.PathEscape(ref)+"?"+query.Encode(), &tree)
+		if err != nil {
+			return result, err
+		}
+		for _, entry := range tree.Tree {
+			if entry.Type == "blob" && isModFilePath(entry.Path) {
+				result = append(result, entry.Path)
+			}
+		}
+		count = count + len(tree.Tree)
+		if !tree.Truncated || len(tree.Tree) == 0 || count >= tree.TotalCount {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (this *giteaClient) GetFile(ctx context.Context, repo Repository, filePath string) ([]byte, error) {
+	endpoint := "/api/v1/repos/" + repo.FullName + "/raw/" + filePath
+	if repo.DefaultBranch != "" {
+		endpoint = endpoint + "?ref=" + url.QueryEscape(repo.DefaultBranch)
+	}
+	resp, err := httpGet(ctx, this.http, this.baseUrl+endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (this *giteaClient) ListRefs(ctx context.Context, repo Repository) ([]*plumbing.Reference, error) {
+	return listRemoteRefs(ctx, repo.CloneUrl, this.gitAuth(), this.requestTimeout)
+}
+
+func (this *giteaClient) gitAuth() transport.AuthMethod {
+	if this.token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "oauth2", Password: this.token}
+}
+
+func (this *giteaClient) getJson(ctx context.Context, endpoint string, result interface{}) error {
+	resp, err := httpGet(ctx, this.http, this.baseUrl+endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
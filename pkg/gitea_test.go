@@ -0,0 +1,65 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"strings"
+	"testing"
+)
+
+func TestGiteaOrg(t *testing.T) {
+	env := testenv.New(t)
+	org := env.Host() + "/org"
+	env.Serve(t, testenv.Description{
+		Orgs: []testenv.Org{{Name: "org", Repos: []testenv.Repo{
+			{
+				Name: "lib",
+				Commits: []testenv.Commit{
+					{Files: map[string]string{"go.mod": goMod(org+"/lib", "1.99")}, Tags: []string{"v1.0.0"}},
+					{Files: map[string]string{"lib.go": "package lib\n"}, Tags: []string{"v1.2.0"}},
+				},
+			},
+			{
+				Name:       "a",
+				NoLanguage: true,
+				Commits: []testenv.Commit{{
+					Files: map[string]string{
+						"go.mod":       goMod(org+"/a", "1.99", org+"/lib v1.0.0"),
+						"tools/go.mod": goMod(org+"/a/tools", "1.21", org+"/lib v1.2.0"),
+						"docs/a.md":    "# a\n",
+						"docs/b.md":    "# b\n",
+					},
+					Tags: []string{"v0.1.0", "tools/v0.2.0"},
+				}},
+			},
+			{Name: "docs", Language: "Python", Commits: []testenv.Commit{{Files: map[string]string{"go.mod": goMod(org+"/docs", "1.1")}}}},
+			{Name: "empty"},
+		}}},
+		GoTags: testGoTags,
+	})
+	config := testConfig(env)
+	config.Forge = ForgeGitea
+	config.GiteaUrl = env.GiteaUrl
+	output := replaceServerUrl(env, runMopher(t, config))
+	//the module names and therefore the commit hashes contain the random port of the server
+	output = strings.ReplaceAll(output, env.Hash(t, "org/lib", "v1.2.0")[:12], "<lib-hash>")
+	checkGolden(t, "gitea_org", output)
+	if env.Requests("/gitea/api/v1/orgs/org/repos") < 3 || env.Requests("/gitea/api/v1/repos/org/a/git/trees/") < 2 {
+		t.Error("expected the repository listing and the tree to be paged")
+	}
+}
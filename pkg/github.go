@@ -18,13 +18,83 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-github/v54/github"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 )
 
-func loadOrgRepos(org string) (result []*github.Repository, err error) {
-	client := github.NewClient(nil)
+type githubClient struct {
+	api            *github.Client
+	http           *http.Client
+	rawUrl         string
+	host           string
+	token          string
+	requestTimeout time.Duration
+}
+
+// newGithubClient creates a client for github.com or, if config.GithubApiUrl is set, for a GitHub Enterprise instance.
+// config.GithubToken is optional and used for the api, raw content and git requests
+func newGithubClient(config MopherConfig) (client *githubClient, err error) {
+	token, apiUrl := config.GithubToken, config.GithubApiUrl
+	client = &githubClient{
+		rawUrl:         config.GithubRawUrl,
+		host:           GithubUrl,
+		token:          token,
+		requestTimeout: config.RequestTimeout,
+	}
+	apiHost := GithubApiHost
+	if apiUrl != "" {
+		parsedApiUrl, err := url.Parse(apiUrl)
+		if err != nil {
+			return client, err
+		}
+		apiHost = parsedApiUrl.Host
+		client.host = parsedApiUrl.Host
+		if config.GithubHost != "" {
+			client.host = config.GithubHost
+		}
+		if client.rawUrl == "" {
+			client.rawUrl = parsedApiUrl.Scheme + "://" + parsedApiUrl.Host + "/raw/"
+		}
+	}
+	if client.rawUrl == "" {
+		client.rawUrl = GithubRawUrl
+	}
+	if !strings.HasSuffix(client.rawUrl, "/") {
+		client.rawUrl = client.rawUrl + "/"
+	}
+	parsedRawUrl, err := url.Parse(client.rawUrl)
+	if err != nil {
+		return client, err
+	}
+	authValue := ""
+	if token != "" {
+		authValue = "Bearer " + token
+	}
+	client.http = newHttpClient(config, "Authorization", authValue, apiHost, parsedRawUrl.Host)
+	if apiUrl == "" {
+		client.api = github.NewClient(client.http)
+	} else {
+		client.api, err = github.NewEnterpriseClient(apiUrl, apiUrl, client.http)
+	}
+	return client, err
+}
+
+func (this *githubClient) Host() string {
+	return this.host
+}
+
+func (this *githubClient) ListRepos(ctx context.Context, org string) (result []Repository, err error) {
 	options := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{
 			Page:    1,
@@ -33,11 +103,26 @@ func loadOrgRepos(org string) (result []*github.Repository, err error) {
 	}
 	for {
 		slog.Debug(fmt.Sprintf("request github %v %v", options.ListOptions.PerPage, options.ListOptions.Page))
-		repos, resp, err := client.Repositories.ListByOrg(context.Background(), org, options)
+		repos, resp, err := this.api.Repositories.ListByOrg(ctx, org, options)
+		if waitForRateLimitReset(ctx, err) {
+			repos, resp, err = this.api.Repositories.ListByOrg(ctx, org, options)
+		}
 		if err != nil {
 			return result, err
 		}
-		result = append(result, repos...)
+		for _, repo := range repos {
+			result = append(result, Repository{
+				Name:          repo.GetName(),
+				FullName:      repo.GetFullName(),
+				DefaultBranch: repo.GetDefaultBranch(),
+				CloneUrl:      repo.GetCloneURL(),
+				HtmlUrl:       repo.GetHTMLURL(),
+				Forge:         ForgeGithub,
+				Language:      repo.GetLanguage(),
+				Archived:      repo.GetArchived(),
+				PushedAt:      repo.GetPushedAt().Time,
+			})
+		}
 		if resp.NextPage == 0 {
 			break
 		} else {
@@ -46,3 +131,79 @@ func loadOrgRepos(org string) (result []*github.Repository, err error) {
 	}
 	return result, nil
 }
+
+func (this *githubClient) ListModFiles(ctx context.Context, repo Repository) (result []string, err error) {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	defaultBranch := "master"
+	if repo.DefaultBranch != "" {
+		defaultBranch = repo.DefaultBranch
+	}
+	tree, resp, err := this.api.Git.GetTree(ctx, owner, name, defaultBranch, true)
+	if waitForRateLimitReset(ctx, err) {
+		tree, resp, err = this.api.Git.GetTree(ctx, owner, name, defaultBranch, true)
+	}
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusConflict) {
+		return result, nil //empty repository
+	}
+	if err != nil {
+		return result, err
+	}
+	if tree.GetTruncated() {
+		slog.Debug("truncated git tree, nested go.mod files may be missing", "repo-name", repo.FullName)
+	}
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && isModFilePath(entry.GetPath()) {
+			result = append(result, entry.GetPath())
+		}
+	}
+	return result, nil
+}
+
+func (this *githubClient) GetFile(ctx context.Context, repo Repository, filePath string) ([]byte, error) {
+	defaultBranch := "master"
+	if repo.DefaultBranch != "" {
+		defaultBranch = repo.DefaultBranch
+	}
+	resp, err := httpGet(ctx, this.http, this.rawUrl+path.Join(repo.FullName, defaultBranch, filePath))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (this *githubClient) ListRefs(ctx context.Context, repo Repository) ([]*plumbing.Reference, error) {
+	return listRemoteRefs(ctx, repo.CloneUrl, this.gitAuth(), this.requestTimeout)
+}
+
+func (this *githubClient) gitAuth() transport.AuthMethod {
+	if this.token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: this.token}
+}
+
+// waitForRateLimitReset waits until the rate limit is reset, if err is a github.RateLimitError.
+// the github client returns this error without sending the request, if a previous response exhausted the rate limit.
+// returns true if the request should be repeated.
+func waitForRateLimitReset(ctx context.Context, err error) bool {
+	var rateErr *github.RateLimitError
+	if !errors.As(err, &rateErr) {
+		return false
+	}
+	wait := time.Until(rateErr.Rate.Reset.Time) + time.Second
+	slog.Info("github rate limit reached, waiting", "wait", wait.String())
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type gitlabClient struct {
+	http           *http.Client
+	baseUrl        string
+	host           string
+	token          string
+	requestTimeout time.Duration
+}
+
+type gitlabProject struct {
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	HttpUrlToRepo     string `json:"http_url_to_repo"`
+	WebUrl            string `json:"web_url"`
+	Archived          bool   `json:"archived"`
+	EmptyRepo         bool   `json:"empty_repo"`
+}
+
+// newGitlabClient creates a client for the GitLab instance at config.GitlabUrl (e.g. https://gitlab.example.com).
+// config.GitlabToken is optional and used for api and git requests
+func newGitlabClient(config MopherConfig) (client *gitlabClient, err error) {
+	token, baseUrl := config.GitlabToken, config.GitlabUrl
+	if baseUrl == "" {
+		return client, errors.New("missing gitlab url")
+	}
+	parsedUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return client, err
+	}
+	client = &gitlabClient{
+		http:           newHttpClient(config, "PRIVATE-TOKEN", token, parsedUrl.Host),
+		baseUrl:        strings.TrimSuffix(baseUrl, "/"),
+		host:           parsedUrl.Host,
+		token:          token,
+		requestTimeout: config.RequestTimeout,
+	}
+	return client, nil
+}
+
+func (this *gitlabClient) Host() string {
+	return this.host
+}
+
+// ListRepos lists the projects of the group and its subgroups
+func (this *gitlabClient) ListRepos(ctx context.Context, group string) (result []Repository, err error) {
+	page := "1"
+	for page != "" {
+		slog.Debug(fmt.Sprintf("request gitlab %v %v", 100, page))
+		query := url.Values{}
+		query.Set("include_subgroups", "true")
+		query.Set("per_page", "100")
+		query.Set("page", page)
+		projects := []gitlabProject{}
+		header, err := this.getJson(ctx, "/api/v4/groups/"+url.PathEscape(group)+"/projects?"+query.Encode(), &projects)
+		if err != nil {
+			return result, err
+		}
+		for _, project := range projects {
+			if project.EmptyRepo {
+				slog.Debug("ignored empty repo", "repo-name", project.PathWithNamespace)
+				continue
+			}
+			result = append(result, Repository{
+				Name:            project.Path,
+				FullName:        project.PathWithNamespace,
+				DefaultBranch:   project.DefaultBranch,
+				CloneUrl:        project.HttpUrlToRepo,
+				HtmlUrl:         project.WebUrl,
+				Forge:           ForgeGitlab,
+				Archived:        project.Archived,
+				LanguageUnknown: true,
+			})
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return result, nil
+}
+
+type gitlabTreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+func (this *gitlabClient) ListModFiles(ctx context.Context, repo Repository) (result []string, err error) {
+	page := "1"
+	for page != "" {
+		query := url.Values{}
+		query.Set("recursive", "true")
+		query.Set("per_page", "100")
+		query.Set("page", page)
+		if repo.DefaultBranch != "" {
+			query.Set("ref", repo.DefaultBranch)
+		}
+		entries := []gitlabTreeEntry{}
+		header, err := this.getJson(ctx, "/api/v4/projects/"+url.PathEscape(repo.FullName)+"/repository/tree?"+query.Encode(), &entries)
+		if err != nil {
+			return result, err
+		}
+		for _, entry := range entries {
+			if entry.Type == "blob" && isModFilePath(entry.Path) {
+				result = append(result, entry.Path)
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return result, nil
+}
+
+func (this *gitlabClient) GetFile(ctx context.Context, repo Repository, filePath string) ([]byte, error) {
+	endpoint := "/api/v4/projects/" + url.PathEscape(repo.FullName) + "/repository/files/" + url.PathEscape(filePath) + "/raw"
+	if repo.DefaultBranch != "" {
+		endpoint = endpoint + "?ref=" + url.QueryEscape(repo.DefaultBranch)
+	}
+	resp, err := httpGet(ctx, this.http, this.baseUrl+endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (this *gitlabClient) ListRefs(ctx context.Context, repo Repository) ([]*plumbing.Reference, error) {
+	return listRemoteRefs(ctx, repo.CloneUrl, this.gitAuth(), this.requestTimeout)
+}
+
+func (this *gitlabClient) gitAuth() transport.AuthMethod {
+	if this.token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "oauth2", Password: this.token}
+}
+
+func (this *gitlabClient) getJson(ctx context.Context, endpoint string, result interface{}) (header http.Header, err error) {
+	resp, err := httpGet(ctx, this.http, this.baseUrl+endpoint)
+	if err != nil {
+		return header, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(resp.Body)
+		return header, fmt.Errorf("unexpected statuscode %v %v", resp.StatusCode, string(payload))
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"strings"
+	"testing"
+)
+
+func TestGitlabGroup(t *testing.T) {
+	env := testenv.New(t)
+	group := env.Host() + "/group"
+	env.Serve(t, testenv.Description{
+		Orgs: []testenv.Org{
+			{Name: "group", Repos: []testenv.Repo{
+				{
+					Name: "lib",
+					Commits: []testenv.Commit{
+						{Files: map[string]string{"go.mod": goMod(group+"/lib", "1.99")}, Tags: []string{"v1.0.0"}},
+						{Files: map[string]string{"lib.go": "package lib\n"}, Tags: []string{"v1.2.0"}},
+					},
+				},
+				{Name: "empty"},
+				{Name: "old", Archived: true, Commits: []testenv.Commit{{Files: map[string]string{"go.mod": goMod(group+"/old", "1.1")}}}},
+			}},
+			{Name: "group/sub", Repos: []testenv.Repo{
+				{
+					Name: "a",
+					Commits: []testenv.Commit{{
+						Files: map[string]string{
+							"go.mod":       goMod(group+"/sub/a", "1.99", group+"/lib v1.0.0"),
+							"tools/go.mod": goMod(group+"/sub/a/tools", "1.21", group+"/lib v1.2.0"),
+						},
+						Tags: []string{"v0.1.0", "tools/v0.2.0"},
+					}},
+				},
+			}},
+			{Name: "other", Repos: []testenv.Repo{goRepo("b", goMod("example.com/other/b", "1.1"))}},
+		},
+		GoTags: testGoTags,
+	})
+	config := testConfig(env)
+	config.Forge = ForgeGitlab
+	config.GitlabUrl = env.GitlabUrl
+	config.Org = "group"
+	output := replaceServerUrl(env, runMopher(t, config))
+	//the module names and therefore the commit hashes contain the random port of the server
+	output = strings.ReplaceAll(output, env.Hash(t, "group/lib", "v1.2.0")[:12], "<lib-hash>")
+	checkGolden(t, "gitlab_group", output)
+	if env.Requests("/gitlab/api/v4/groups/group/projects") < 2 {
+		t.Error("expected the project listing to be paged")
+	}
+
+	config.Org = "group/sub"
+	output = replaceServerUrl(env, runMopher(t, config))
+	if !strings.Contains(output, "forge.example/group/sub/a/tools") || strings.Contains(output, "forge.example/group/lib ") {
+		t.Errorf("expected only the modules of the subgroup, got:\n%v", output)
+	}
+}
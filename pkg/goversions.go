@@ -17,9 +17,10 @@
 package pkg
 
 import (
-	"fmt"
+	"context"
 	"golang.org/x/mod/semver"
 	"log/slog"
+	"net/http"
 	"regexp"
 	"runtime"
 	"slices"
@@ -27,18 +28,9 @@ import (
 	"strings"
 )
 
-func (this *Parsed) PrintGoVersionWarnings() (deprecated []string, err error) {
-	checkedGoVersion := normalizeGoVersion(getLatestGoVersion())
-	list, err := this.listOldGoVersionUsage(checkedGoVersion)
-	if err != nil {
-		return deprecated, err
-	}
-	if len(list) > 0 {
-		_, err = fmt.Fprintln(this.output, "\n\nthe following repositories use a go version !=", checkedGoVersion)
-		if err != nil {
-			return deprecated, err
-		}
-	}
+func (this *Parsed) GetGoVersionFindings() (result []Finding) {
+	checkedGoVersion := this.getExpectedGoVersion()
+	list := this.listOldGoVersionUsage(checkedGoVersion)
 	slices.SortFunc(list, func(a, b VersionUsageRef) int {
 		result := strings.Compare(a.Version, b.Version)
 		if result == 0 {
@@ -47,16 +39,25 @@ func (this *Parsed) PrintGoVersionWarnings() (deprecated []string, err error) {
 		return result
 	})
 	for _, e := range list {
-		_, err = fmt.Fprintln(this.output, e.Version, e.Name)
-		if err != nil {
-			return deprecated, err
-		}
-		deprecated = append(deprecated, e.Name)
+		result = append(result, Finding{
+			Kind:            FindingOutdatedGoVersion,
+			Module:          e.Name,
+			UsedVersion:     e.Version,
+			ExpectedVersion: checkedGoVersion,
+		})
 	}
-	return deprecated, nil
+	return result
 }
 
-func (this *Parsed) listOldGoVersionUsage(checkedGoVersion string) (result []VersionUsageRef, err error) {
+// getExpectedGoVersion returns LatestGoVersion or the version of the running go runtime, if LatestGoVersion is not loaded
+func (this *Parsed) getExpectedGoVersion() string {
+	if this.LatestGoVersion == "" {
+		return normalizeGoVersion(runtime.Version())
+	}
+	return this.LatestGoVersion
+}
+
+func (this *Parsed) listOldGoVersionUsage(checkedGoVersion string) (result []VersionUsageRef) {
 	//make result deterministic by sorting the keys
 	keys := []string{}
 	for key, _ := range this.Latest {
@@ -81,7 +82,7 @@ func (this *Parsed) listOldGoVersionUsage(checkedGoVersion string) (result []Ver
 			})
 		}
 	}
-	return result, nil
+	return result
 }
 
 func normalizeGoVersion(version string) string {
@@ -94,9 +95,9 @@ func normalizeGoVersion(version string) string {
 	return version
 }
 
-func getLatestGoVersion() string {
+func getLatestGoVersion(ctx context.Context, client *http.Client, tagsUrl string) string {
 	buildVersion := normalizeGoVersion(runtime.Version())
-	tags, err := getGolangTagsFromDockerhub()
+	tags, err := getGolangTagsFromDockerhub(ctx, client, tagsUrl)
 	if err != nil {
 		slog.Debug("unable to load tags from dockerhub:", "err", err)
 		slog.Debug("fallback to mopher build go version")
@@ -109,7 +110,7 @@ func getLatestGoVersion() string {
 		return semver.Compare(ensureSemverComparable(b), ensureSemverComparable(a))
 	})
 	slog.Debug("getLatestGoVersion()", "used-tag", tags[0], "known-tags", tags)
-	return tags[0]
+	return normalizeGoVersion(tags[0])
 }
 
 func dockerhubTagCleanup(tags []string) (result []string) {
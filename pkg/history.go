@@ -0,0 +1,364 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// HistoryEntry is the result of a single run, stored as one line of the history file (see MopherConfig.HistoryFile)
+type HistoryEntry struct {
+	Time              time.Time       `json:"time"`
+	Org               string          `json:"org"`
+	ExpectedGoVersion string          `json:"expected_go_version"`
+	Modules           []ModuleHistory `json:"modules"`
+	Findings          []Finding       `json:"findings"`
+	Errors            []string        `json:"errors,omitempty"` //repositories that could not be checked; GetHistorySummary uses their modules of the previous entry
+}
+
+type ModuleHistory struct {
+	Module               string `json:"module"`
+	Repo                 string `json:"repo,omitempty"`
+	GoVersion            string `json:"go_version"`            //"missing" if the go.mod file has no go directive
+	OutdatedDependencies int    `json:"outdated_dependencies"` //number of outdated org dependencies, independent of ReportOptions.MinLag
+	MaxLag               Lag    `json:"max_lag"`               //highest lag of the outdated org dependencies, unknown if no lag is known
+}
+
+// HistorySummary aggregates the history entries of an org by week and by module
+type HistorySummary struct {
+	Org     string                `json:"org"`
+	Weeks   []WeekSummary         `json:"weeks"`
+	Modules []ModuleUpdateSummary `json:"modules"`
+}
+
+// WeekSummary describes the last run of the week
+type WeekSummary struct {
+	Week              string `json:"week"` //ISO week, e.g. 2026-W09
+	Runs              int    `json:"runs"`
+	Modules           int    `json:"modules"`
+	Outdated          int    `json:"outdated"` //modules with at least one outdated org dependency
+	BehindMajor       int    `json:"behind_major"`
+	BehindMinor       int    `json:"behind_minor"`
+	BehindPatch       int    `json:"behind_patch"`
+	ExpectedGoVersion string `json:"expected_go_version"`
+	OnExpectedGo      int    `json:"on_expected_go"` //modules using ExpectedGoVersion
+	Findings          int    `json:"findings"`
+}
+
+type ModuleUpdateSummary struct {
+	Module              string        `json:"module"`
+	Updates             int           `json:"updates"`                  //number of times the module went from outdated to up-to-date
+	AverageTimeToUpdate time.Duration `json:"average_time_to_update"`   //average duration of the completed outdated periods
+	OutdatedSince       *time.Time    `json:"outdated_since,omitempty"` //start of the current outdated period
+}
+
+func (this *Parsed) GetHistoryEntry(report Report) HistoryEntry {
+	result := HistoryEntry{
+		Time:              this.scannedAt,
+		Org:               this.org,
+		ExpectedGoVersion: this.getExpectedGoVersion(),
+		Modules:           []ModuleHistory{},
+		Findings:          report.Findings,
+		Errors:            getErrorRepos(this.Errors),
+	}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+	outdated := map[string][]Finding{}
+	for _, f := range this.GetDependencyVersionFindings() {
+		outdated[f.Module] = append(outdated[f.Module], f)
+	}
+	for _, name := range getSortedKeys(this.Modules) {
+		module := ModuleHistory{
+			Module:               name,
+			Repo:                 this.Sources[name].Repo,
+			GoVersion:            "missing",
+			OutdatedDependencies: len(outdated[name]),
+			MaxLag:               LagUpToDate,
+		}
+		if this.Modules[name].Go != nil {
+			module.GoVersion = normalizeGoVersion(this.Modules[name].Go.Version)
+		}
+		if len(outdated[name]) > 0 {
+			module.MaxLag = LagUnknown
+		}
+		for _, f := range outdated[name] {
+			if f.Lag != LagUnknown && (module.MaxLag == LagUnknown || f.Lag.AtLeast(module.MaxLag)) {
+				module.MaxLag = f.Lag
+			}
+		}
+		result.Modules = append(result.Modules, module)
+	}
+	return result
+}
+
+// AppendHistory appends the entry as json line to the file
+func AppendHistory(file string, entry HistoryEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(content, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func ReadHistory(file string) (result []HistoryEntry, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	for {
+		entry := HistoryEntry{}
+		err = decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid history entry %v in %v: %w", len(result)+1, file, err)
+		}
+		result = append(result, entry)
+	}
+}
+
+// GetHistorySummary expects entries of a single org
+func GetHistorySummary(org string, entries []HistoryEntry) HistorySummary {
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, func(a, b HistoryEntry) int {
+		return a.Time.Compare(b.Time)
+	})
+	addHistoryOfFailedRepos(entries)
+	result := HistorySummary{Org: org, Weeks: []WeekSummary{}, Modules: []ModuleUpdateSummary{}}
+
+	for _, entry := range entries {
+		year, week := entry.Time.ISOWeek()
+		summary := WeekSummary{
+			Week:              fmt.Sprintf("%04d-W%02d", year, week),
+			Runs:              1,
+			Modules:           len(entry.Modules),
+			ExpectedGoVersion: entry.ExpectedGoVersion,
+			Findings:          len(entry.Findings),
+		}
+		for _, module := range entry.Modules {
+			if module.OutdatedDependencies > 0 {
+				summary.Outdated++
+			}
+			switch module.MaxLag {
+			case LagBehindMajor:
+				summary.BehindMajor++
+			case LagBehindMinor:
+				summary.BehindMinor++
+			case LagBehindPatch:
+				summary.BehindPatch++
+			}
+			if module.GoVersion == entry.ExpectedGoVersion {
+				summary.OnExpectedGo++
+			}
+		}
+		if last := len(result.Weeks) - 1; last >= 0 && result.Weeks[last].Week == summary.Week {
+			summary.Runs += result.Weeks[last].Runs
+			result.Weeks[last] = summary
+		} else {
+			result.Weeks = append(result.Weeks, summary)
+		}
+	}
+
+	modules := map[string]bool{}
+	outdatedSince := map[string]time.Time{}
+	updates := map[string][]time.Duration{}
+	for _, entry := range entries {
+		for _, module := range entry.Modules {
+			modules[module.Module] = true
+			since, outdated := outdatedSince[module.Module]
+			switch {
+			case module.OutdatedDependencies > 0 && !outdated:
+				outdatedSince[module.Module] = entry.Time
+			case module.OutdatedDependencies == 0 && outdated:
+				updates[module.Module] = append(updates[module.Module], entry.Time.Sub(since))
+				delete(outdatedSince, module.Module)
+			}
+		}
+	}
+	for _, name := range getSortedKeys(modules) {
+		summary := ModuleUpdateSummary{Module: name, Updates: len(updates[name])}
+		if summary.Updates > 0 {
+			var sum time.Duration
+			for _, d := range updates[name] {
+				sum += d
+			}
+			summary.AverageTimeToUpdate = sum / time.Duration(summary.Updates)
+		}
+		if since, ok := outdatedSince[name]; ok {
+			summary.OutdatedSince = &since
+		}
+		result.Modules = append(result.Modules, summary)
+	}
+	return result
+}
+
+// addHistoryOfFailedRepos adds the modules and findings of repositories that could not be checked from the previous entry
+// (see getModulesOfFailedRepos). entries must be sorted by time
+func addHistoryOfFailedRepos(entries []HistoryEntry) {
+	moduleRepos := func(modules []ModuleHistory) map[string]string {
+		result := map[string]string{}
+		for _, module := range modules {
+			result[module.Module] = module.Repo
+		}
+		return result
+	}
+	for i := 1; i < len(entries); i++ {
+		if len(entries[i].Errors) == 0 {
+			continue
+		}
+		kept := getModulesOfFailedRepos(moduleRepos(entries[i-1].Modules), moduleRepos(entries[i].Modules), entries[i].Errors)
+		modules := slices.Clone(entries[i].Modules)
+		for _, module := range entries[i-1].Modules {
+			if _, ok := kept[module.Module]; ok {
+				modules = append(modules, module)
+			}
+		}
+		slices.SortFunc(modules, func(a, b ModuleHistory) int {
+			return strings.Compare(a.Module, b.Module)
+		})
+		entries[i].Modules = modules
+		entries[i].Findings = keepFindingsOfModules(entries[i].Findings, entries[i-1].Findings, kept)
+	}
+}
+
+func EncodeHistorySummary(summary HistorySummary, encoding string) (string, error) {
+	switch encoding {
+	case EncodingJsonData:
+		temp, err := json.Marshal(summary)
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingJson:
+		text, err := EncodeHistorySummary(summary, EncodingText)
+		if err != nil {
+			return "", err
+		}
+		temp, err := json.Marshal(strings.TrimSpace(text))
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	default:
+		buf := strings.Builder{}
+		err := WriteTextHistorySummary(&buf, summary)
+		return buf.String(), err
+	}
+}
+
+func WriteTextHistorySummary(out io.Writer, summary HistorySummary) (err error) {
+	_, err = fmt.Fprintf(out, "\n\nhistory of %v per week (last run of the week):\n", summary.Org)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, err = fmt.Fprintln(w, "week\truns\tmodules\toutdated\tbehind-major\tbehind-minor\tbehind-patch\tgo-version\tfindings")
+	if err != nil {
+		return err
+	}
+	for _, week := range summary.Weeks {
+		_, err = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v/%v on %v\t%v\n", week.Week, week.Runs, week.Modules, week.Outdated,
+			week.BehindMajor, week.BehindMinor, week.BehindPatch, week.OnExpectedGo, week.Modules, week.ExpectedGoVersion, week.Findings)
+		if err != nil {
+			return err
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, "\n\ntime to update org dependencies per module:")
+	if err != nil {
+		return err
+	}
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, err = fmt.Fprintln(w, "module\tupdates\tavg-time-to-update\toutdated-since")
+	if err != nil {
+		return err
+	}
+	for _, module := range summary.Modules {
+		avg, since := "-", "-"
+		if module.Updates > 0 {
+			avg = formatDays(module.AverageTimeToUpdate)
+		}
+		if module.OutdatedSince != nil {
+			since = module.OutdatedSince.Format(time.DateOnly)
+		}
+		_, err = fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", module.Module, module.Updates, avg, since)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func formatDays(d time.Duration) string {
+	return fmt.Sprintf("%.1fd", d.Hours()/24)
+}
+
+// MopherHistory writes the HistorySummary of config.HistoryFile to the configured output.
+// if config.Org is set, only entries of this org are used
+func MopherHistory(ctx context.Context, config MopherConfig) error {
+	if config.HistoryFile == "" {
+		return errors.New("missing history file")
+	}
+	tmpl, err := template.New("templ").Parse(config.OutputTemplate)
+	if err != nil {
+		return err
+	}
+	entries, err := ReadHistory(config.HistoryFile)
+	if err != nil {
+		return err
+	}
+	org := config.Org
+	if org == "" && len(entries) > 0 {
+		org = entries[len(entries)-1].Org
+	}
+	entries = slices.DeleteFunc(entries, func(entry HistoryEntry) bool {
+		return entry.Org != org
+	})
+	summary := GetHistorySummary(org, entries)
+	output, err := EncodeHistorySummary(summary, config.OutputEncode)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, config, tmpl, output, map[string]interface{}{"History": summary})
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func readTestHistory(t *testing.T, file string) []HistoryEntry {
+	t.Helper()
+	entries, err := ReadHistory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestHistoryFile(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "history.jsonl")
+	a := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0"))
+
+	config := testConfig(startOrg(t, libRepo(), a))
+	config.HistoryFile = historyFile
+	runMopher(t, config)
+	entries := readTestHistory(t, historyFile)
+	if len(entries) != 1 || len(entries[0].Modules) != 2 || len(entries[0].Errors) != 0 {
+		t.Fatalf("unexpected history %#v", entries)
+	}
+
+	// a repository that can not be checked keeps its modules of the previous entry in the summary
+	config = testConfig(startOrg(t, libRepo(), unavailable(a)))
+	config.HistoryFile = historyFile
+	runMopher(t, config)
+	entries = readTestHistory(t, historyFile)
+	if len(entries) != 2 || !slices.Equal(entries[1].Errors, []string{"org/a"}) {
+		t.Fatalf("unexpected history %#v", entries)
+	}
+	summary := GetHistorySummary("org", entries)
+	week := summary.Weeks[len(summary.Weeks)-1]
+	if week.Modules != 2 || week.Outdated != 1 || week.BehindPatch != 1 || week.Findings != 1 {
+		t.Errorf("unexpected week summary %#v", week)
+	}
+
+	// listing errors append no entry
+	config = testConfig(startOrg(t, libRepo(), a))
+	config.HistoryFile = historyFile
+	config.Org = "unknown"
+	if err := Mopher(context.Background(), config); err == nil {
+		t.Error("expected listing error")
+	}
+	if entries = readTestHistory(t, historyFile); len(entries) != 2 {
+		t.Errorf("expected 2 entries after listing error, got %v", len(entries))
+	}
+}
+
+func TestHistoryFileFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "history.jsonl")
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+	config := testConfig(startOrg(t, libRepo()))
+	config.SaveSnapshot = snapshotFile
+	runMopher(t, config)
+	snapshot, err := ReadSnapshotFile(snapshotFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the entry of a replayed snapshot uses the time of the scan
+	config = MopherConfig{LoadSnapshot: snapshotFile, HistoryFile: historyFile, OutputTemplate: "{{.Output}}", OutputEncode: EncodingText}
+	runMopher(t, config)
+	entries := readTestHistory(t, historyFile)
+	if len(entries) != 1 || !entries[0].Time.Equal(snapshot.CreatedAt) {
+		t.Fatalf("expected one entry at %v, got %#v", snapshot.CreatedAt, entries)
+	}
+}
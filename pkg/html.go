@@ -0,0 +1,315 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteHtmlReport renders the report as a single static html file without external resources:
+// sortable tables per check, module filter, search box, update order and an svg dependency graph
+func WriteHtmlReport(out io.Writer, report Report) error {
+	modules := map[string]bool{}
+	for _, f := range report.Findings {
+		modules[f.Module] = true
+	}
+	for _, e := range report.Edges {
+		modules[e.Module] = true
+		modules[e.Dependency] = true
+	}
+	for _, d := range report.Dependents {
+		modules[d.Module] = true
+	}
+	return htmlReportTemplate.Execute(out, map[string]interface{}{
+		"Report":             report,
+		"Modules":            getSortedKeys(modules),
+		"WrongModuleNames":   report.FindingsOfKind(FindingWrongModuleName),
+		"GoVersions":         report.FindingsOfKind(FindingOutdatedGoVersion),
+		"UnsyncedBranches":   report.FindingsOfKind(FindingUnsyncedDevBranch),
+		"OutdatedDependency": report.FindingsOfKind(FindingOutdatedDependency),
+		"Graph":              template.HTML(renderDependencyGraphSvg(report)),
+	})
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"moduleLink": func(report Report, name string, fallbackUrl string) template.HTML {
+		url := fallbackUrl
+		if source, ok := report.Sources[name]; ok {
+			url = source.Url
+		}
+		if !strings.HasPrefix(url, "http") {
+			return template.HTML(template.HTMLEscapeString(name))
+		}
+		return template.HTML(`<a href="` + template.HTMLEscapeString(url) + `">` + template.HTMLEscapeString(name) + `</a>`)
+	},
+	"inc": func(i int) int {
+		return i + 1
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>mopher report for {{.Report.Org}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; font-family: monospace; }
+th { background: #eee; cursor: pointer; user-select: none; font-family: sans-serif; }
+th[data-order="asc"]::after { content: " \25B2"; }
+th[data-order="desc"]::after { content: " \25BC"; }
+.controls { position: sticky; top: 0; background: #fff; padding: 0.5em 0; }
+.controls input, .controls select { margin-right: 1em; }
+.empty { color: #888; }
+.hidden { display: none; }
+svg .node rect { fill: #f4f4f4; stroke: #888; }
+svg .node.findings rect { fill: #ffe0b2; stroke: #e65100; }
+svg .node.selected rect { stroke: #1565c0; stroke-width: 3; }
+svg .node { cursor: pointer; }
+svg .node text { font-family: monospace; font-size: 12px; }
+svg .edge { fill: none; stroke: #999; }
+svg .edge.outdated { stroke: #e65100; }
+svg .edge.dimmed, svg .node.dimmed { opacity: 0.2; }
+</style>
+</head>
+<body>
+<h1>mopher report for {{.Report.Org}}</h1>
+<div class="controls">
+<label>module <select id="module-filter"><option value="">all modules</option>{{range .Modules}}<option>{{.}}</option>{{end}}</select></label>
+<label>search <input id="search" type="search" placeholder="module, version, error..."></label>
+</div>
+{{$report := .Report}}
+{{if .Report.Dependency}}
+<h2>Usage of {{.Report.Dependency}}</h2>
+{{if .Report.Dependents}}
+<table class="sortable"><thead><tr><th>module</th><th>version</th></tr></thead><tbody>
+{{range .Report.Dependents}}<tr data-modules="{{.Module}}"><td>{{moduleLink $report .Module ""}}</td><td>{{.Version}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">{{.Report.Dependency}} is used by no {{.Report.Org}} repository as dependency</p>{{end}}
+{{end}}
+<h2>Unexpected module names ({{len .WrongModuleNames}})</h2>
+{{if .WrongModuleNames}}
+<table class="sortable"><thead><tr><th>module</th><th>repository</th></tr></thead><tbody>
+{{range .WrongModuleNames}}<tr data-modules="{{.Module}}"><td>{{.Module}}</td><td>{{if .RepoUrl}}<a href="{{.RepoUrl}}">{{.RepoUrl}}</a>{{end}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">none</p>{{end}}
+<h2>Outdated go versions ({{len .GoVersions}})</h2>
+{{if .GoVersions}}
+<table class="sortable"><thead><tr><th>module</th><th>used</th><th>latest</th><th>update order</th></tr></thead><tbody>
+{{range .GoVersions}}<tr data-modules="{{.Module}}"><td>{{moduleLink $report .Module .RepoUrl}}</td><td>{{.UsedVersion}}</td><td>{{.ExpectedVersion}}</td><td>{{.UpdateOrderPosition}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">none</p>{{end}}
+<h2>Unsynced dev branches ({{len .UnsyncedBranches}})</h2>
+{{if .UnsyncedBranches}}
+<table class="sortable"><thead><tr><th>module</th><th>dev</th><th>master/main</th></tr></thead><tbody>
+{{range .UnsyncedBranches}}<tr data-modules="{{.Module}}"><td>{{moduleLink $report .Module .RepoUrl}}</td><td>{{.UsedVersion}}</td><td>{{.ExpectedVersion}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">none</p>{{end}}
+<h2>Outdated org dependencies ({{len .OutdatedDependency}})</h2>
+{{if .OutdatedDependency}}
+<table class="sortable"><thead><tr><th>module</th><th>dependency</th><th>used</th><th>latest</th><th>lag</th><th>update order</th></tr></thead><tbody>
+{{range .OutdatedDependency}}<tr data-modules="{{.Module}} {{.Dependency}}"><td>{{moduleLink $report .Module .RepoUrl}}</td><td>{{moduleLink $report .Dependency ""}}</td><td>{{.UsedVersion}}</td><td>{{.ExpectedVersion}}</td><td>{{.Lag}}</td><td>{{.UpdateOrderPosition}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">none</p>{{end}}
+{{if .Report.Errors}}
+<h2>Repositories that could not be checked ({{len .Report.Errors}})</h2>
+<table class="sortable"><thead><tr><th>repository</th><th>phase</th><th>error</th></tr></thead><tbody>
+{{range .Report.Errors}}<tr data-modules=""><td>{{.Repo}}</td><td>{{.Phase}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody></table>
+{{end}}
+<h2>Recommended update order ({{len .Report.UpdateOrder}})</h2>
+{{if .Report.UpdateOrder}}
+<table class="sortable"><thead><tr><th>position</th><th>module</th></tr></thead><tbody>
+{{range $i, $m := .Report.UpdateOrder}}<tr data-modules="{{$m}}"><td>{{inc $i}}</td><td>{{moduleLink $report $m ""}}</td></tr>
+{{end}}</tbody></table>
+{{else}}<p class="empty">nothing to update</p>{{end}}
+<h2>Dependency graph</h2>
+<p>arrows point from a module to its org dependencies, orange edges are outdated, orange modules have findings. click a module to filter.</p>
+{{.Graph}}
+<script>
+(function () {
+	var filter = document.getElementById("module-filter");
+	var search = document.getElementById("search");
+	function apply() {
+		var module = filter.value;
+		var text = search.value.toLowerCase();
+		document.querySelectorAll("tr[data-modules]").forEach(function (row) {
+			var modules = row.getAttribute("data-modules").split(" ");
+			var visible = (module === "" || modules.indexOf(module) >= 0) && (text === "" || row.textContent.toLowerCase().indexOf(text) >= 0);
+			row.classList.toggle("hidden", !visible);
+		});
+		document.querySelectorAll("svg .node").forEach(function (node) {
+			var name = node.getAttribute("data-module");
+			node.classList.toggle("selected", module !== "" && name === module);
+			node.classList.toggle("dimmed", text !== "" && name.toLowerCase().indexOf(text) < 0);
+		});
+		document.querySelectorAll("svg .edge").forEach(function (edge) {
+			var related = edge.getAttribute("data-module") === module || edge.getAttribute("data-dependency") === module;
+			edge.classList.toggle("dimmed", module !== "" && !related);
+		});
+	}
+	filter.addEventListener("change", apply);
+	search.addEventListener("input", apply);
+	document.querySelectorAll("svg .node").forEach(function (node) {
+		node.addEventListener("click", function () {
+			var name = node.getAttribute("data-module");
+			filter.value = filter.value === name ? "" : name;
+			apply();
+		});
+	});
+	document.querySelectorAll("table.sortable th").forEach(function (th) {
+		th.addEventListener("click", function () {
+			var table = th.closest("table");
+			var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+			var order = th.getAttribute("data-order") === "asc" ? "desc" : "asc";
+			table.querySelectorAll("th").forEach(function (other) { other.removeAttribute("data-order"); });
+			th.setAttribute("data-order", order);
+			var body = table.tBodies[0];
+			var rows = Array.prototype.slice.call(body.rows);
+			rows.sort(function (a, b) {
+				var x = a.cells[index].textContent, y = b.cells[index].textContent;
+				var result = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y, undefined, {numeric: true});
+				return order === "asc" ? result : -result;
+			});
+			rows.forEach(function (row) { body.appendChild(row); });
+		});
+	});
+})();
+</script>
+</body>
+</html>
+`))
+
+const (
+	graphNodeHeight  = 24
+	graphRowGap      = 12
+	graphColumnGap   = 80
+	graphPadding     = 10
+	graphCharWidth   = 7.3
+	graphTextPadding = 16
+)
+
+type graphNode struct {
+	name     string
+	x, y     float64
+	width    float64
+	findings int
+}
+
+// renderDependencyGraphSvg places the org modules in columns by their dependency depth
+// (modules without org dependencies on the left) and draws an edge per org dependency
+func renderDependencyGraphSvg(report Report) string {
+	dependencies := map[string][]string{}
+	names := map[string]bool{}
+	for _, e := range report.Edges {
+		dependencies[e.Module] = append(dependencies[e.Module], e.Dependency)
+		names[e.Module] = true
+		names[e.Dependency] = true
+	}
+	findings := map[string]int{}
+	outdated := map[string]bool{}
+	for _, f := range report.Findings {
+		findings[f.Module]++
+		names[f.Module] = true
+		if f.Kind == FindingOutdatedDependency {
+			outdated[f.Module+" "+f.Dependency] = true
+		}
+	}
+	if len(names) == 0 {
+		return `<p class="empty">no org modules</p>`
+	}
+
+	depth := map[string]int{}
+	visiting := map[string]bool{}
+	var getDepth func(name string) int
+	getDepth = func(name string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if visiting[name] {
+			return 0 //cycles are reported by the update order
+		}
+		visiting[name] = true
+		result := 0
+		for _, dep := range dependencies[name] {
+			result = max(result, getDepth(dep)+1)
+		}
+		depth[name] = result
+		return result
+	}
+
+	columns := [][]*graphNode{}
+	for _, name := range getSortedKeys(names) {
+		d := getDepth(name)
+		for len(columns) <= d {
+			columns = append(columns, []*graphNode{})
+		}
+		columns[d] = append(columns[d], &graphNode{
+			name:     name,
+			width:    float64(len(name))*graphCharWidth + graphTextPadding,
+			findings: findings[name],
+		})
+	}
+
+	nodes := map[string]*graphNode{}
+	x := float64(graphPadding)
+	height := 0.0
+	for _, column := range columns {
+		columnWidth := 0.0
+		for i, node := range column {
+			node.x = x
+			node.y = float64(graphPadding + i*(graphNodeHeight+graphRowGap))
+			columnWidth = max(columnWidth, node.width)
+			height = max(height, node.y+graphNodeHeight+graphPadding)
+			nodes[node.name] = node
+		}
+		x += columnWidth + graphColumnGap
+	}
+	width := x - graphColumnGap + graphPadding
+
+	buf := strings.Builder{}
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n", width, height, width, height)
+	buf.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#999"/></marker></defs>` + "\n")
+	for _, e := range report.Edges {
+		from, to := nodes[e.Module], nodes[e.Dependency]
+		x1, y1 := from.x, from.y+graphNodeHeight/2
+		x2, y2 := to.x+to.width, to.y+graphNodeHeight/2
+		class := "edge"
+		if outdated[e.Module+" "+e.Dependency] {
+			class += " outdated"
+		}
+		fmt.Fprintf(&buf, `<path class="%v" data-module="%v" data-dependency="%v" marker-end="url(#arrow)" d="M %.1f %.1f C %.1f %.1f, %.1f %.1f, %.1f %.1f"><title>%v</title></path>`+"\n",
+			class, template.HTMLEscapeString(e.Module), template.HTMLEscapeString(e.Dependency),
+			x1, y1, x1-graphColumnGap/2, y1, x2+graphColumnGap/2, y2, x2, y2,
+			template.HTMLEscapeString(e.Module+" uses "+e.Dependency+" "+e.Version))
+	}
+	for _, column := range columns {
+		for _, node := range column {
+			class := "node"
+			if node.findings > 0 {
+				class += " findings"
+			}
+			name := template.HTMLEscapeString(node.name)
+			fmt.Fprintf(&buf, `<g class="%v" data-module="%v"><title>%v (findings: %v)</title><rect x="%.1f" y="%.1f" width="%.1f" height="%v" rx="4"/><text x="%.1f" y="%.1f">%v</text></g>`+"\n",
+				class, name, name, node.findings, node.x, node.y, node.width, graphNodeHeight, node.x+graphTextPadding/2, node.y+graphNodeHeight/2+4, name)
+		}
+	}
+	buf.WriteString("</svg>")
+	return buf.String()
+}
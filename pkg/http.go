@@ -0,0 +1,185 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// newHttpClient creates the client used for forge and docker hub requests.
+// if authValue is not empty, requests to authHosts get the authHeader with this value;
+// other hosts (e.g. redirect targets) never get the credentials.
+// if config.CacheDir is set, responses are cached on disk and revalidated with conditional requests.
+// config.RequestTimeout limits each attempt, waiting for retries or rate limit resets is only limited by the request context.
+func newHttpClient(config MopherConfig, authHeader string, authValue string, authHosts ...string) *http.Client {
+	var transport http.RoundTripper = &retryTransport{
+		base:           http.DefaultTransport,
+		maxRetries:     config.MaxRetries,
+		requestTimeout: config.RequestTimeout,
+		minBackoff:     time.Second,
+	}
+	if config.CacheDir != "" {
+		transport = newCacheTransport(config.CacheDir, authValue, transport)
+	}
+	if authValue != "" {
+		transport = &headerTransport{header: authHeader, value: authValue, hosts: authHosts, base: transport}
+	}
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+func httpGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// headerTransport adds the header to requests to one of the hosts
+type headerTransport struct {
+	header string
+	value  string
+	hosts  []string
+	base   http.RoundTripper
+}
+
+func (this *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slices.Contains(this.hosts, req.URL.Host) {
+		return this.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(this.header, this.value)
+	return this.base.RoundTrip(req)
+}
+
+// retryTransport retries GET requests on network errors, 5xx responses and rate limits (429 or 403 with
+// Retry-After or exhausted X-RateLimit-Remaining). rate limits are waited out, other errors use exponential backoff with jitter.
+type retryTransport struct {
+	base           http.RoundTripper
+	maxRetries     int
+	requestTimeout time.Duration
+	minBackoff     time.Duration
+}
+
+func (this *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := this.roundTripAttempt(req)
+		if err == nil {
+			logRateLimit(req, resp)
+		}
+		wait, retry := this.getRetryWait(req, resp, err, attempt)
+		if !retry || attempt >= this.maxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slog.Debug("retry request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait.String(), "err", err)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func (this *retryTransport) roundTripAttempt(req *http.Request) (*http.Response, error) {
+	if this.requestTimeout <= 0 {
+		return this.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), this.requestTimeout)
+	resp, err := this.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (this *retryTransport) getRetryWait(req *http.Request, resp *http.Response, err error, attempt int) (wait time.Duration, retry bool) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return 0, false
+	}
+	if err != nil {
+		return this.backoff(attempt), req.Context().Err() == nil
+	}
+	if wait, limited := getRateLimitWait(resp); limited {
+		slog.Info("rate limit reached, waiting", "host", req.URL.Host, "wait", wait.String())
+		return wait, true
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return this.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns minBackoff * 2^attempt plus up to 50% jitter
+func (this *retryTransport) backoff(attempt int) time.Duration {
+	wait := this.minBackoff << min(attempt, 10)
+	return wait + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func getRateLimitWait(resp *http.Response) (wait time.Duration, limited bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return max(time.Until(date), 0), true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+func logRateLimit(req *http.Request, resp *http.Response) {
+	if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+		slog.Debug("rate limit", "host", req.URL.Host, "remaining", remaining, "limit", resp.Header.Get("X-RateLimit-Limit"), "reset", resp.Header.Get("X-RateLimit-Reset"))
+	}
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (this *cancelOnCloseBody) Close() error {
+	defer this.cancel()
+	return this.ReadCloser.Close()
+}
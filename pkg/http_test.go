@@ -0,0 +1,151 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetRateLimitWait(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		limited bool
+		min     time.Duration
+		max     time.Duration
+	}{
+		{name: "ok", status: http.StatusOK, header: map[string]string{"Retry-After": "5"}},
+		{name: "forbidden", status: http.StatusForbidden},
+		{name: "retry after seconds", status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "5"}, limited: true, min: 5 * time.Second, max: 5 * time.Second},
+		{name: "secondary rate limit", status: http.StatusForbidden, header: map[string]string{"Retry-After": "3"}, limited: true, min: 3 * time.Second, max: 3 * time.Second},
+		{name: "retry after past date", status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "Mon, 01 Jan 2024 12:00:00 GMT"}, limited: true},
+		{
+			name:    "exhausted rate limit",
+			status:  http.StatusForbidden,
+			header:  map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10)},
+			limited: true,
+			min:     9 * time.Second,
+			max:     11 * time.Second,
+		},
+		{name: "past rate limit reset", status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1704110400"}, limited: true, min: time.Second, max: time.Second},
+		{name: "exhausted rate limit without reset", status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "0"}},
+		{name: "remaining rate limit", status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "1704110400"}},
+		{name: "too many requests", status: http.StatusTooManyRequests, limited: true, min: time.Minute, max: time.Minute},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: c.status, Header: http.Header{}}
+			for key, value := range c.header {
+				resp.Header.Set(key, value)
+			}
+			wait, limited := getRateLimitWait(resp)
+			if limited != c.limited || wait < c.min || wait > c.max {
+				t.Errorf("expected limited=%v with wait in [%v, %v], got limited=%v with wait %v", c.limited, c.min, c.max, limited, wait)
+			}
+		})
+	}
+}
+
+// startStatusServer answers the requests with the statuses in order and repeats the last one
+func startStatusServer(t *testing.T, statuses ...int) (server *httptest.Server, requests *atomic.Int32) {
+	requests = &atomic.Int32{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		index := min(int(requests.Add(1))-1, len(statuses)-1)
+		w.WriteHeader(statuses[index])
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestRetryTransport(t *testing.T) {
+	cases := []struct {
+		name     string
+		statuses []int
+		status   int
+		requests int32
+	}{
+		{name: "retry server error", statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, status: http.StatusOK, requests: 2},
+		{name: "max retries", statuses: []int{http.StatusBadGateway}, status: http.StatusBadGateway, requests: 3},
+		{name: "no retry on client error", statuses: []int{http.StatusNotFound, http.StatusOK}, status: http.StatusNotFound, requests: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, requests := startStatusServer(t, c.statuses...)
+			client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, maxRetries: 2, minBackoff: time.Millisecond}}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.status || requests.Load() != c.requests {
+				t.Errorf("expected status %v after %v requests, got %v after %v", c.status, c.requests, resp.StatusCode, requests.Load())
+			}
+		})
+	}
+}
+
+func TestGithubTokenHosts(t *testing.T) {
+	env := startOrg(t, libRepo(), goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0")))
+	config := testConfig(env)
+	config.GithubToken = "secret"
+	runMopher(t, config)
+	for _, prefix := range []string{"/api/v3/", "/raw/"} {
+		requests := env.GetRequests(prefix)
+		if len(requests) == 0 {
+			t.Errorf("no %v requests", prefix)
+		}
+		for _, request := range requests {
+			if request.Header.Get("Authorization") != "Bearer secret" {
+				t.Errorf("missing token in request of %v", request.Path)
+			}
+		}
+	}
+	for _, request := range env.GetRequests("/dockerhub/") {
+		if request.Header.Get("Authorization") != "" {
+			t.Errorf("unexpected token in request of %v", request.Path)
+		}
+	}
+}
+
+func TestTokenIsNotSentToRedirectTargets(t *testing.T) {
+	headers := map[string]string{}
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers["target"] = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(target.Close)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers["api"] = r.Header.Get("Authorization")
+		http.Redirect(w, r, target.URL+"/file", http.StatusFound)
+	}))
+	t.Cleanup(api.Close)
+
+	client := newHttpClient(MopherConfig{}, "Authorization", "Bearer secret", api.Listener.Addr().String())
+	resp, err := client.Get(api.URL + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if headers["api"] != "Bearer secret" || headers["target"] != "" {
+		t.Errorf("expected the token only in the api request, got %v", headers)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheTransport stores GET responses with ETag or Last-Modified header in dir
+// and revalidates them with If-None-Match/If-Modified-Since.
+// a 304 response is answered with the cached body; its headers (e.g. rate limits) replace the cached ones.
+type cacheTransport struct {
+	dir     string
+	authKey string //hash of the credentials, so that responses are not shared between different tokens
+	base    http.RoundTripper
+}
+
+type httpCacheEntry struct {
+	Url          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+func newCacheTransport(dir string, authValue string, base http.RoundTripper) *cacheTransport {
+	authHash := sha256.Sum256([]byte(authValue))
+	return &cacheTransport{dir: dir, authKey: hex.EncodeToString(authHash[:]), base: base}
+}
+
+// ClearHttpCache removes all responses stored in the cache dir, including temporary files of interrupted writes
+func ClearHttpCache(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".json" || strings.Contains(entry.Name(), ".json.tmp-") {
+			err = os.Remove(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return this.base.RoundTrip(req)
+	}
+	url := req.URL.String()
+	file := this.getFileName(url)
+	cached, ok := this.load(file, url)
+	if ok {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := this.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		slog.Debug("http cache hit", "url", url)
+		_ = resp.Body.Close()
+		header := cached.Header.Clone()
+		for key, values := range resp.Header {
+			header[key] = values
+		}
+		return cached.toResponse(req, header), nil
+	}
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := httpCacheEntry{
+		Url:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+	}
+	err = this.store(file, entry)
+	if err != nil {
+		slog.Warn("unable to store http cache entry", "url", url, "err", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (this *cacheTransport) getFileName(url string) string {
+	hash := sha256.Sum256([]byte(this.authKey + " " + url))
+	return filepath.Join(this.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+func (this *cacheTransport) load(file string, url string) (entry httpCacheEntry, ok bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return entry, false
+	}
+	err = json.Unmarshal(content, &entry)
+	if err != nil || entry.Url != url {
+		slog.Debug("ignore invalid http cache entry", "file", file, "err", err)
+		return entry, false
+	}
+	return entry, true
+}
+
+// store writes the entry atomically, so that parallel runs never read partial entries
+func (this *cacheTransport) store(file string, entry httpCacheEntry) error {
+	err := os.MkdirAll(this.dir, 0o700)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, content)
+}
+
+func (this httpCacheEntry) toResponse(req *http.Request, header http.Header) *http.Response {
+	header.Del("Content-Encoding")
+	return &http.Response{
+		Status:        http.StatusText(this.StatusCode),
+		StatusCode:    this.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(this.Body)),
+		ContentLength: int64(len(this.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHttpCache(t *testing.T) {
+	env := startOrg(t, libRepo(), goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0")))
+	config := testConfig(env)
+	config.CacheDir = t.TempDir()
+	config.GithubToken = "token-a"
+	expected := runMopher(t, config)
+
+	// the second run revalidates every cached response
+	served := len(env.GetRequests("/"))
+	if actual := runMopher(t, config); actual != expected {
+		t.Errorf("report with cached responses differs\nexpected:\n%v\nactual:\n%v", expected, actual)
+	}
+	revalidated := 0
+	for _, request := range env.GetRequests("/")[served:] {
+		if request.Path == "/api/v3/orgs/org/repos" || filepath.Dir(request.Path) == "/raw/org/a/main" {
+			if request.Header.Get("If-None-Match") == "" || request.Status != 304 {
+				t.Errorf("expected revalidation of %v, got If-None-Match %q and status %v", request.Path, request.Header.Get("If-None-Match"), request.Status)
+			}
+			revalidated++
+		}
+	}
+	if revalidated != 2 {
+		t.Errorf("expected the org listing and the go.mod of org/a to be requested, got %v requests", revalidated)
+	}
+
+	// responses of authenticated requests are not shared between tokens
+	served = len(env.GetRequests("/"))
+	config.GithubToken = "token-b"
+	runMopher(t, config)
+	for _, request := range env.GetRequests("/")[served:] {
+		if !strings.HasPrefix(request.Path, "/api/") && !strings.HasPrefix(request.Path, "/raw/") {
+			continue
+		}
+		if request.Header.Get("If-None-Match") != "" || request.Status != 200 {
+			t.Errorf("unexpected revalidation of %v with the cache entry of another token", request.Path)
+		}
+	}
+}
+
+func TestClearHttpCache(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json.tmp-123", "other.txt"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := ClearHttpCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "other.txt" {
+		t.Errorf("expected only other.txt to be kept, got %v", entries)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (this roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return this(req)
+}
+
+func TestHttpCacheMergesNotModifiedHeader(t *testing.T) {
+	remaining := []string{"59", "58"}
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("content")), Request: req}
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			resp.StatusCode = http.StatusNotModified
+			resp.Body = http.NoBody
+		}
+		resp.Header.Set("ETag", `"v1"`)
+		resp.Header.Set("X-RateLimit-Remaining", remaining[0])
+		remaining = remaining[1:]
+		return resp, nil
+	})
+	client := &http.Client{Transport: newCacheTransport(t.TempDir(), "token", base)}
+	for _, expected := range []string{"59", "58"} {
+		resp, err := client.Get("https://forge.example/file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(body) != "content" || resp.Header.Get("X-RateLimit-Remaining") != expected {
+			t.Errorf("unexpected response %v %q with X-RateLimit-Remaining %v", resp.StatusCode, string(body), resp.Header.Get("X-RateLimit-Remaining"))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type JunitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []JunitTestSuite `xml:"testsuite"`
+}
+
+type JunitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []JunitTestCase `xml:"testcase"`
+}
+
+type JunitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *JunitFailure `xml:"failure,omitempty"`
+	Error     *JunitFailure `xml:"error,omitempty"`
+}
+
+type JunitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJunitReport writes the report as JUnit XML: every org module is a test case (class name = repository)
+// with a single failure listing all findings of the module; repositories that could not be checked are test cases with an error
+func WriteJunitReport(out io.Writer, report Report) error {
+	suite := JunitTestSuite{Name: report.Org}
+	findings := map[string][]Finding{}
+	for _, f := range report.Findings {
+		findings[f.Module] = append(findings[f.Module], f)
+	}
+	for _, module := range getSortedKeys(report.Sources) {
+		source := report.Sources[module]
+		testCase := JunitTestCase{
+			ClassName: source.Repo,
+			Name:      module,
+			File:      source.Path,
+		}
+		if list := findings[module]; len(list) > 0 {
+			testCase.Failure = getJunitFailure(source, list)
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	for _, e := range report.Errors {
+		suite.TestCases = append(suite.TestCases, JunitTestCase{
+			ClassName: e.Repo,
+			Name:      e.Repo,
+			Error: &JunitFailure{
+				Type:    string(e.Phase),
+				Message: e.Message,
+				Text:    e.Message,
+			},
+		})
+		suite.Errors++
+	}
+	suite.Tests = len(suite.TestCases)
+
+	_, err := io.WriteString(out, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(JunitTestSuites{
+		Name:     "mopher",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Suites:   []JunitTestSuite{suite},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "\n")
+	return err
+}
+
+// getJunitFailure describes all findings of a module, because test cases have at most one failure
+func getJunitFailure(source ModuleSource, findings []Finding) *JunitFailure {
+	kinds := []string{}
+	lines := []string{}
+	for _, f := range findings {
+		if !slices.Contains(kinds, string(f.Kind)) {
+			kinds = append(kinds, string(f.Kind))
+		}
+		line := f.String()
+		if f.Line > 0 {
+			line = fmt.Sprintf("%v:%v: %v", source.Path, f.Line, line)
+		}
+		lines = append(lines, line)
+	}
+	result := &JunitFailure{
+		Type:    strings.Join(kinds, ","),
+		Message: findings[0].String(),
+		Text:    strings.Join(lines, "\n"),
+	}
+	if len(findings) > 1 {
+		result.Message = fmt.Sprintf("%v findings: %v", len(findings), strings.Join(kinds, ", "))
+	}
+	if source.Url != "" {
+		result.Text += "\n" + source.Url
+	}
+	return result
+}
@@ -17,102 +17,207 @@
 package pkg
 
 import (
-	"errors"
-	"github.com/google/go-github/v54/github"
+	"context"
 	"golang.org/x/mod/modfile"
 	"log/slog"
-	"strings"
 	"sync"
+	"time"
 )
 
-func LoadOrg(org string, maxConn int) (parsed *Parsed, err error) {
-	parsed = &Parsed{
-		org:     org,
-		Modules: map[string]*modfile.File{},
-		Inverse: map[string][]InverseIndexModRef{},
-		Latest:  map[string]LatestCommitInfo{},
-	}
-	parsed.Repos, err = loadOrgRepos(org)
+func LoadOrg(ctx context.Context, config MopherConfig) (parsed *Parsed, err error) {
+	var source RepoSource
+	source, err = NewRepoSource(config)
 	if err != nil {
 		return parsed, err
 	}
-	parsed.Modules, parsed.Latest, err = getRepoInfos(parsed.Repos, maxConn)
+	if !config.At.IsZero() {
+		source = newTimeTravelSource(source, config.At)
+	}
+	return LoadOrgFromSource(ctx, source, config)
+}
+
+// LoadOrgFromSource aborts with the context error if ctx is done before all repositories are checked.
+// a failing listing of the org repositories is returned as error, because the result would be empty instead of partial
+func LoadOrgFromSource(ctx context.Context, source RepoSource, config MopherConfig) (parsed *Parsed, err error) {
+	org := config.Org
+	parsed = &Parsed{
+		org:               org,
+		host:              source.Host(),
+		includePrerelease: config.IncludePrerelease,
+		scannedAt:         time.Now(),
+		Modules:           map[string]*modfile.File{},
+		ModFiles:          map[string][]byte{},
+		Inverse:           map[string][]InverseIndexModRef{},
+		Latest:            map[string]LatestCommitInfo{},
+		Sources:           map[string]ModuleSource{},
+	}
+	parsed.Repos, err = source.ListRepos(ctx, org)
+	if ctx.Err() != nil {
+		return parsed, ctx.Err()
+	}
 	if err != nil {
-		return parsed, err
+		return parsed, withPhase(PhaseListing, err)
+	}
+	var previous *Snapshot
+	if config.StateFile != "" && !config.At.IsZero() {
+		slog.Info("state file is not used for time travel scans")
+		config.StateFile = ""
 	}
-	for name, module := range parsed.Modules {
+	if config.StateFile != "" {
+		previous = loadScanState(config.StateFile, parsed)
+	}
+	parsed.loadRepoInfos(ctx, source, config.MaxConn, config.IncludePrerelease, previous)
+	if ctx.Err() != nil {
+		return parsed, ctx.Err()
+	}
+	sortRepoErrors(parsed.Errors)
+	if config.StateFile != "" {
+		err = WriteSnapshotFile(config.StateFile, parsed.GetSnapshot())
+		if err != nil {
+			slog.Warn("unable to store scan state", "file", config.StateFile, "err", err)
+		}
+	}
+	if config.FailOnRepoErrors && len(parsed.Errors) > 0 {
+		return parsed, JoinRepoErrors(parsed.Errors)
+	}
+	if config.WarnGoVersion {
+		parsed.LatestGoVersion = getLatestGoVersion(ctx, newHttpClient(config, "", ""), config.DockerhubUrl)
+	}
+	parsed.buildInverseIndex()
+	return parsed, nil
+}
+
+func (this *Parsed) buildInverseIndex() {
+	for name, module := range this.Modules {
 		for _, req := range module.Require {
 			version, semantic := normalizeGoDependencyVersion(req.Mod.Version)
-			parsed.Inverse[req.Mod.Path] = append(parsed.Inverse[req.Mod.Path], InverseIndexModRef{
+			this.Inverse[req.Mod.Path] = append(this.Inverse[req.Mod.Path], InverseIndexModRef{
 				UsesVersion:     version,
+				RawVersion:      req.Mod.Version,
 				UserModule:      name,
 				SemanticVersion: semantic,
+				Indirect:        req.Indirect,
 			})
 		}
 	}
-
-	return parsed, nil
 }
 
-func getRepoInfos(repos []*github.Repository, maxConn int) (modules map[string]*modfile.File, latestInfo map[string]LatestCommitInfo, err error) {
-	modules = map[string]*modfile.File{}
-	latestInfo = map[string]LatestCommitInfo{}
+// loadRepoInfos continues on failing repositories and collects their errors in Parsed.Errors.
+// repositories that are unchanged since the previous scan reuse its results (previous may be nil)
+func (this *Parsed) loadRepoInfos(ctx context.Context, source RepoSource, maxConn int, includePrerelease bool, previous *Snapshot) {
 	mux := sync.Mutex{}
 	wg := sync.WaitGroup{}
-	asyncErrors := []error{}
 	limit := make(chan bool, maxConn)
-	for _, repo := range repos {
-		if repo.Language != nil && *repo.Language == "Go" && (repo.Archived == nil || *repo.Archived == false) {
+	reused := 0
+	for _, repo := range this.Repos {
+		if (repo.Language == "Go" || repo.LanguageUnknown) && !repo.Archived {
+			if modules, ok := previous.getRepoModules(repo); ok {
+				slog.Debug("reuse unchanged repo", "repo-name", repo.FullName, "pushed-at", repo.PushedAt)
+				mux.Lock()
+				this.addRepoModules(repo, modules)
+				mux.Unlock()
+				reused++
+				continue
+			}
 			wg.Add(1)
-			go func(r *github.Repository) {
+			go func(r Repository) {
 				defer wg.Done()
-				limit <- true
+				select {
+				case limit <- true:
+				case <-ctx.Done():
+					return
+				}
 				defer func() {
 					<-limit
 				}()
-				name, module, err := parseRepoModuleFile(r)
-				if err == ErrModfileNotFound {
-					return
-				}
-				if err != nil {
-					asyncErrors = append(asyncErrors, err)
-					return
+				if releaser, ok := source.(repoReleaser); ok {
+					defer releaser.release(r)
 				}
-				latest, err := getLatestInfo(r)
+				modules, fileErrs, err := getRepoModules(ctx, source, r, includePrerelease)
 				mux.Lock()
 				defer mux.Unlock()
+				for _, fileErr := range fileErrs {
+					slog.Debug("unable to check go.mod file", "repo-name", r.FullName, "err", fileErr)
+					this.Errors = append(this.Errors, newRepoError(r.FullName, fileErr))
+				}
 				if err != nil {
-					asyncErrors = append(asyncErrors, err)
+					slog.Debug("unable to check repo", "repo-name", r.FullName, "err", err)
+					this.Errors = append(this.Errors, newRepoError(r.FullName, err))
 					return
 				}
-				modules[name] = module
-				latestInfo[name] = latest
+				this.addRepoModules(r, modules)
 			}(repo)
 		} else {
-			name := ""
-			if repo.Name != nil {
-				name = *repo.Name
-			}
-			language := ""
-			if repo.Language != nil {
-				language = *repo.Language
-			}
-			slog.Debug("ignored repo", "repo-name", name, "language", language)
+			slog.Debug("ignored repo", "repo-name", repo.Name, "language", repo.Language, "archived", repo.Archived)
 		}
 	}
 	wg.Wait()
-	if len(asyncErrors) > 0 {
-		return modules, latestInfo, errors.Join(asyncErrors...)
+	if previous != nil {
+		slog.Info("incremental scan", "reused-repos", reused, "scanned-repos", len(this.Repos)-reused)
+	}
+}
+
+func (this *Parsed) addRepoModules(repo Repository, modules map[string]repoModule) {
+	for filePath, module := range modules {
+		name := module.file.Module.Mod.Path
+		this.Modules[name] = module.file
+		this.ModFiles[name] = module.content
+		this.Latest[name] = module.latest
+		this.Sources[name] = ModuleSource{
+			Repo:  repo.FullName,
+			Url:   repo.HtmlUrl,
+			Path:  filePath,
+			Forge: repo.Forge,
+		}
 	}
-	return modules, latestInfo, nil
 }
 
-func normalizeGoDependencyVersion(version string) (string, bool) {
-	parts := strings.Split(version, "-")
-	switch len(parts) {
-	case 1:
-		return version, true
-	default:
-		return parts[len(parts)-1], false
+type repoModule struct {
+	content []byte
+	file    *modfile.File
+	latest  LatestCommitInfo
+}
+
+// getRepoModules returns all modules of the repository and their latest commit info, indexed by the go.mod file path.
+// sub-modules use tags prefixed with their directory (e.g. api/v1.4.0 for api/go.mod).
+// go.mod files that can not be fetched or parsed are returned as fileErrs and do not affect the other modules of the repository
+func getRepoModules(ctx context.Context, source RepoSource, repo Repository, includePrerelease bool) (modules map[string]repoModule, fileErrs []error, err error) {
+	modules = map[string]repoModule{}
+	filePaths, err := source.ListModFiles(ctx, repo)
+	if err != nil {
+		return modules, fileErrs, withPhase(PhaseModFetch, err)
+	}
+	if len(filePaths) == 0 {
+		slog.Debug("ignored repo without go.mod", "repo-name", repo.Name)
+		return modules, fileErrs, nil
+	}
+	for _, filePath := range filePaths {
+		content, module, err := parseRepoModuleFile(ctx, source, repo, filePath)
+		if err == ErrModfileNotFound {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return modules, fileErrs, err
+			}
+			fileErrs = append(fileErrs, err)
+			continue
+		}
+		modules[filePath] = repoModule{content: content, file: module}
+	}
+	if len(modules) == 0 {
+		return modules, fileErrs, nil
+	}
+	refs, err := source.ListRefs(ctx, repo)
+	if err != nil {
+		return modules, fileErrs, withPhase(PhaseLsRemote, err)
+	}
+	for filePath, module := range modules {
+		module.latest, err = getLatestInfoFromRefs(refs, newTagSelection(module.file.Module.Mod.Path, filePath, includePrerelease))
+		if err != nil {
+			return modules, fileErrs, withPhase(PhaseLsRemote, err)
+		}
+		modules[filePath] = module
 	}
+	return modules, fileErrs, nil
 }
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localSource scans a directory containing checkouts of the org repositories and works without network access
+type localSource struct {
+	dir  string
+	host string
+}
+
+func newLocalSource(dir string, host string) (*localSource, error) {
+	if dir == "" {
+		return nil, errors.New("missing local dir")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dir + " is not a directory")
+	}
+	if host == "" {
+		host = GithubUrl
+	}
+	return &localSource{dir: dir, host: host}, nil
+}
+
+func (this *localSource) Host() string {
+	return this.host
+}
+
+// ListRepos returns every git repository (directory containing .git) below the local dir; the org is not used
+func (this *localSource) ListRepos(_ context.Context, _ string) (result []Repository, err error) {
+	err = filepath.WalkDir(this.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(p, git.GitDirName)); err != nil {
+			if p != this.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		repo, err := this.getRepository(p)
+		if err != nil {
+			return err
+		}
+		result = append(result, repo)
+		return filepath.SkipDir
+	})
+	return result, err
+}
+
+func (this *localSource) getRepository(dir string) (result Repository, err error) {
+	rel, err := filepath.Rel(this.dir, dir)
+	if err != nil {
+		return result, err
+	}
+	gitRepo, err := git.PlainOpen(dir)
+	if err != nil {
+		return result, err
+	}
+	result = Repository{
+		Name:            filepath.Base(dir),
+		FullName:        filepath.ToSlash(rel),
+		CloneUrl:        dir,
+		HtmlUrl:         dir,
+		Forge:           ForgeLocal,
+		LanguageUnknown: true,
+	}
+	head, err := gitRepo.Reference(plumbing.HEAD, false)
+	if err == nil && head.Type() == plumbing.SymbolicReference {
+		result.DefaultBranch = head.Target().Short()
+	}
+	//ssh and file urls of the origin are no usable links, these repositories keep the local path
+	origin, err := gitRepo.Remote("origin")
+	if err == nil && len(origin.Config().URLs) > 0 {
+		originUrl := origin.Config().URLs[0]
+		if strings.HasPrefix(originUrl, "https://") || strings.HasPrefix(originUrl, "http://") {
+			result.HtmlUrl = strings.TrimSuffix(originUrl, ".git")
+		}
+	}
+	return result, nil
+}
+
+// ListModFiles lists the go.mod files of the HEAD commit, so that uncommitted changes are ignored like the refs
+func (this *localSource) ListModFiles(_ context.Context, repo Repository) (result []string, err error) {
+	commit, err := getLocalHeadCommit(repo)
+	if err != nil || commit == nil {
+		return result, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return result, err
+	}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if isModFilePath(file.Name) {
+			result = append(result, file.Name)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// GetFile reads the file from the HEAD commit of the checkout
+func (this *localSource) GetFile(_ context.Context, repo Repository, filePath string) ([]byte, error) {
+	commit, err := getLocalHeadCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	if commit == nil {
+		return nil, ErrFileNotFound
+	}
+	file, err := commit.File(filePath)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	return []byte(content), err
+}
+
+// getLocalHeadCommit returns the commit of HEAD or nil if the repository has no commits
+func getLocalHeadCommit(repo Repository) (*object.Commit, error) {
+	gitRepo, err := git.PlainOpen(repo.CloneUrl)
+	if err != nil {
+		return nil, err
+	}
+	head, err := gitRepo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gitRepo.CommitObject(head.Hash())
+}
+
+// ListRefs reads HEAD, branch and tag refs from the local .git directory.
+// branches that only exist as remote tracking branches (refs/remotes/origin/...) are used as local branches
+func (this *localSource) ListRefs(_ context.Context, repo Repository) (refs []*plumbing.Reference, err error) {
+	gitRepo, err := git.PlainOpen(repo.CloneUrl)
+	if err != nil {
+		return refs, err
+	}
+	iter, err := gitRepo.References()
+	if err != nil {
+		return refs, err
+	}
+	remoteBranches := map[plumbing.ReferenceName]*plumbing.Reference{}
+	localBranches := map[plumbing.ReferenceName]bool{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		switch {
+		case strings.HasPrefix(name, "refs/remotes/origin/"):
+			branch := plumbing.NewBranchReferenceName(strings.TrimPrefix(name, "refs/remotes/origin/"))
+			if ref.Type() == plumbing.HashReference {
+				remoteBranches[branch] = plumbing.NewHashReference(branch, ref.Hash())
+			}
+		case ref.Name().IsBranch():
+			localBranches[ref.Name()] = true
+			refs = append(refs, ref)
+		default:
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return refs, err
+	}
+	for name, ref := range remoteBranches {
+		if !localBranches[name] {
+			refs = append(refs, ref)
+		}
+	}
+	slog.Debug("read local refs " + repo.CloneUrl)
+	return refs, nil
+}
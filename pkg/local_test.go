@@ -0,0 +1,87 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"github.com/go-git/go-git/v5/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalSource(t *testing.T) {
+	dir := t.TempDir()
+	addOrigin := func(repo testenv.Repo, dir string, origin string) {
+		t.Helper()
+		_, err := testenv.BuildCheckout(t, dir, repo).CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{origin}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addOrigin(libRepo(), filepath.Join(dir, "lib"), "https://github.com/org/lib.git")
+	addOrigin(goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.0.0")), filepath.Join(dir, "nested", "a"), "git@github.com:org/a.git")
+	testenv.BuildCheckout(t, filepath.Join(dir, ".cache", "hidden"), goRepo("hidden", goMod("github.com/org/hidden", "1.1")))
+	testenv.BuildCheckout(t, filepath.Join(dir, "empty"), testenv.Repo{Name: "empty"})
+	err := os.MkdirAll(filepath.Join(dir, "plain"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// uncommitted changes are ignored
+	err = os.WriteFile(filepath.Join(dir, "nested", "a", "go.mod"), []byte(goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.3")), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := newLocalSource(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := source.ListRepos(context.Background(), "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := map[string]string{}
+	for _, repo := range repos {
+		urls[repo.FullName] = repo.HtmlUrl
+	}
+	expected := map[string]string{
+		"empty":    filepath.Join(dir, "empty"),
+		"lib":      "https://github.com/org/lib",
+		"nested/a": filepath.Join(dir, "nested", "a"),
+	}
+	if len(urls) != len(expected) {
+		t.Errorf("expected repositories %v, got %v", expected, urls)
+	}
+	for name, url := range expected {
+		if urls[name] != url {
+			t.Errorf("expected url %v for %v, got %q", url, name, urls[name])
+		}
+	}
+
+	config := MopherConfig{
+		Org:            "org",
+		Forge:          ForgeLocal,
+		LocalDir:       dir,
+		MaxConn:        4,
+		OutputTemplate: "{{.Output}}",
+		OutputEncode:   EncodingText,
+		WarnUnsyncDev:  true,
+	}
+	checkGolden(t, "local_dir", runMopher(t, config))
+}
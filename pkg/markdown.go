@@ -0,0 +1,185 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"golang.org/x/mod/module"
+	"io"
+	"strings"
+)
+
+// WriteMarkdownReport renders the report with a heading and a table per check, suitable for GitHub issues and PR comments
+func WriteMarkdownReport(out io.Writer, report Report) error {
+	w := &markdownWriter{out: out}
+	w.printf("## mopher report for %v\n", report.Org)
+
+	if report.Dependency != "" {
+		w.printf("\n### Usage of %v\n\n", markdownCode(report.Dependency))
+		if len(report.Dependents) == 0 {
+			w.printf("%v is used by no %v repository as dependency\n", markdownCode(report.Dependency), report.Org)
+		} else {
+			w.tableHeader("module", "version")
+			for _, ref := range report.Dependents {
+				w.tableRow(markdownModuleLink(report, ref.Module, ""), markdownCode(ref.Version))
+			}
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingWrongModuleName); len(list) > 0 {
+		w.printf("\n### Unexpected module names\n\n")
+		w.tableHeader("module", "repository")
+		for _, f := range list {
+			w.tableRow(markdownCode(f.Module), markdownLink(report.Sources[f.Module].Repo, f.RepoUrl))
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingOutdatedGoVersion); len(list) > 0 {
+		w.printf("\n### Outdated go versions\n\n")
+		w.tableHeader("module", "used", "latest")
+		for _, f := range list {
+			w.tableRow(markdownModuleLink(report, f.Module, f.RepoUrl), markdownCode(f.UsedVersion), markdownCode(f.ExpectedVersion))
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingUnsyncedDevBranch); len(list) > 0 {
+		w.printf("\n### Unsynced dev branches\n\n")
+		w.tableHeader("module", "dev", "master/main", "compare")
+		for _, f := range list {
+			w.tableRow(markdownModuleLink(report, f.Module, f.RepoUrl), markdownCode(f.UsedVersion), markdownCode(f.ExpectedVersion),
+				markdownLink("compare", getCompareUrl(report.Sources[f.Module], f.ExpectedVersion, f.UsedVersion)))
+		}
+	}
+
+	if list := report.FindingsOfKind(FindingOutdatedDependency); len(list) > 0 {
+		w.printf("\n### Outdated org dependencies\n")
+		currentDep := ""
+		for _, f := range list {
+			if f.Dependency != currentDep {
+				currentDep = f.Dependency
+				latest := report.Latest[currentDep]
+				w.printf("\n#### %v\n\n", markdownModuleLink(report, currentDep, ""))
+				w.printf("latest: %v %v\n\n", markdownCode(latest.LatestTag), markdownCode(latest.MainHash))
+				w.tableHeader("module", "used", "latest", "lag", "compare")
+			}
+			w.tableRow(markdownModuleLink(report, f.Module, f.RepoUrl), markdownCode(f.UsedVersion), markdownCode(f.ExpectedVersion), string(f.Lag),
+				markdownLink(fmt.Sprintf("%v...%v", f.UsedVersion, f.ExpectedVersion), getDependencyCompareUrl(report, f)))
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		w.printf("\n### Repositories that could not be checked\n\n")
+		w.tableHeader("repository", "phase", "error")
+		for _, e := range report.Errors {
+			w.tableRow(e.Repo, string(e.Phase), markdownCode(e.Message))
+		}
+	}
+
+	w.printf("\n<details>\n<summary>recommended update order (%v modules)</summary>\n\n", len(report.UpdateOrder))
+	for i, name := range report.UpdateOrder {
+		w.printf("%v. %v\n", i+1, markdownModuleLink(report, name, ""))
+	}
+	w.printf("\n</details>\n")
+	return w.err
+}
+
+type markdownWriter struct {
+	out io.Writer
+	err error
+}
+
+// printf keeps the first error, so that rendering code does not need to check every write
+func (this *markdownWriter) printf(format string, args ...interface{}) {
+	if this.err == nil {
+		_, this.err = fmt.Fprintf(this.out, format, args...)
+	}
+}
+
+func (this *markdownWriter) tableHeader(columns ...string) {
+	this.tableRow(columns...)
+	separators := make([]string, len(columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	this.tableRow(separators...)
+}
+
+func (this *markdownWriter) tableRow(cells ...string) {
+	this.printf("| %v |\n", strings.Join(cells, " | "))
+}
+
+var markdownCellReplacer = strings.NewReplacer("|", "\\|", "\n", " ", "`", "'")
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + markdownCellReplacer.Replace(text) + "`"
+}
+
+func markdownLink(text string, url string) string {
+	text = markdownCellReplacer.Replace(text)
+	if url == "" {
+		return text
+	}
+	return "[" + strings.NewReplacer("[", "\\[", "]", "\\]").Replace(text) + "](" + url + ")"
+}
+
+// markdownModuleLink links the module to its repository; repoUrl is used if the module is not an org module
+func markdownModuleLink(report Report, name string, repoUrl string) string {
+	if source, ok := report.Sources[name]; ok {
+		repoUrl = source.Url
+	}
+	if repoUrl == "" || !strings.HasPrefix(repoUrl, "http") {
+		return markdownCode(name)
+	}
+	return "[" + markdownCode(name) + "](" + repoUrl + ")"
+}
+
+// getCompareUrl returns the compare view between two refs in the repository of source
+func getCompareUrl(source ModuleSource, from string, to string) string {
+	if !strings.HasPrefix(source.Url, "http") || from == "" || to == "" || from == to {
+		return ""
+	}
+	switch source.Forge {
+	case ForgeGitlab:
+		return strings.TrimSuffix(source.Url, "/") + "/-/compare/" + from + "..." + to
+	case ForgeLocal:
+		return ""
+	default:
+		return strings.TrimSuffix(source.Url, "/") + "/compare/" + from + "..." + to
+	}
+}
+
+// getDependencyCompareUrl compares the used with the expected version in the repository of the dependency.
+// semantic versions of sub-modules are prefixed with the module directory (e.g. api/v1.3.0)
+func getDependencyCompareUrl(report Report, f Finding) string {
+	source, ok := report.Sources[f.Dependency]
+	if !ok {
+		return ""
+	}
+	_, pathMajor, _ := module.SplitPathVersion(f.Dependency)
+	prefix := getModuleTagPrefix(source.Path, pathMajor)
+	from, to := f.UsedVersion, f.ExpectedVersion
+	if strings.HasPrefix(from, "v") {
+		from = prefix + from
+	}
+	if strings.HasPrefix(to, "v") {
+		to = prefix + to
+	}
+	return getCompareUrl(source, from, to)
+}
@@ -17,42 +17,67 @@
 package pkg
 
 import (
+	"context"
 	"errors"
-	"github.com/google/go-github/v54/github"
+	"fmt"
 	"golang.org/x/mod/modfile"
-	"io"
-	"net/http"
 	"path"
+	"strings"
 )
 
-var ErrModfileNotFound = errors.New("no go.mod file in root of default branch of the repository")
+var ErrModfileNotFound = errors.New("no go.mod file in default branch of the repository")
 
-func parseRepoModuleFile(repo *github.Repository) (moduleName string, module *modfile.File, err error) {
-	fullName := ""
-	if repo.FullName != nil {
-		fullName = *repo.FullName
-	} else {
-		return moduleName, module, errors.New("missing repo FullName")
-	}
-	defaultBranch := "master"
-	if repo.DefaultBranch != nil {
-		defaultBranch = *repo.DefaultBranch
-	}
-	resp, err := http.Get(GithubRawUrl + path.Join(fullName, defaultBranch, "go.mod"))
-	if err != nil {
-		return moduleName, module, err
+// parseRepoModuleFile returns the raw content and the parsed go.mod file at filePath of the default branch
+func parseRepoModuleFile(ctx context.Context, source RepoSource, repo Repository, filePath string) (content []byte, module *modfile.File, err error) {
+	if repo.FullName == "" {
+		return content, module, errors.New("missing repo FullName")
 	}
-	if resp.StatusCode == http.StatusNotFound {
-		return moduleName, module, ErrModfileNotFound
+	content, err = source.GetFile(ctx, repo, filePath)
+	if errors.Is(err, ErrFileNotFound) {
+		return content, module, ErrModfileNotFound
 	}
-	file, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return moduleName, module, err
+		return content, module, withPhase(PhaseModFetch, fmt.Errorf("%v: %w", filePath, err))
 	}
-	module, err = modfile.ParseLax("go.mod", file, nil)
+	module, err = parseModFile(filePath, content)
+	return content, module, err
+}
+
+func parseModFile(filePath string, content []byte) (module *modfile.File, err error) {
+	module, err = modfile.ParseLax(filePath, content, nil)
 	if err != nil {
-		return moduleName, module, err
+		return module, withPhase(PhaseParse, err)
+	}
+	if module.Module == nil {
+		return module, withPhase(PhaseParse, errors.New("missing module directive in "+filePath))
+	}
+	return module, nil
+}
+
+// isModFilePath checks if the repository relative path is a go.mod file of a module.
+// directories ignored by the go tool (vendor, testdata, names starting with '.' or '_') are skipped
+func isModFilePath(filePath string) bool {
+	parts := strings.Split(filePath, "/")
+	if parts[len(parts)-1] != "go.mod" {
+		return false
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		if dir == "vendor" || dir == "testdata" || strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// getModuleTagPrefix returns the prefix of version tags of the module defined by the go.mod file path.
+// a major version subdirectory (e.g. v2/go.mod for a module path ending with /v2) is not part of the prefix
+func getModuleTagPrefix(filePath string, pathMajor string) string {
+	dir := path.Dir(filePath)
+	if pathMajor != "" && strings.HasPrefix(pathMajor, "/") && path.Base(dir) == pathMajor[1:] {
+		dir = path.Dir(dir)
+	}
+	if dir == "." {
+		return ""
 	}
-	moduleName = module.Module.Mod.Path
-	return moduleName, module, nil
+	return dir + "/"
 }
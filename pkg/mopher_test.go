@@ -0,0 +1,382 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+var testGoTags = []string{"1.99.1", "1.99", "1.99rc2", "1.98-bookworm", "latest"}
+
+func goMod(module string, goVersion string, requires ...string) string {
+	result := "module " + module + "\n"
+	if goVersion != "" {
+		result += "\ngo " + goVersion + "\n"
+	}
+	if len(requires) > 0 {
+		result += "\nrequire (\n"
+		for _, req := range requires {
+			result += "\t" + req + "\n"
+		}
+		result += ")\n"
+	}
+	return result
+}
+
+func goRepo(name string, goModContent string, tags ...string) testenv.Repo {
+	return testenv.Repo{
+		Name: name,
+		Commits: []testenv.Commit{{
+			Files: map[string]string{"go.mod": goModContent},
+			Tags:  tags,
+		}},
+	}
+}
+
+// libRepo has the tags v1.0.0, v1.2.0 and v1.2.3 on subsequent commits
+func libRepo() testenv.Repo {
+	mod := goMod("github.com/org/lib", "1.99")
+	return testenv.Repo{
+		Name: "lib",
+		Commits: []testenv.Commit{
+			{Files: map[string]string{"go.mod": mod}, Tags: []string{"v1.0.0"}},
+			{Files: map[string]string{"lib.go": "package lib\n"}, Tags: []string{"v1.2.0"}},
+			{Files: map[string]string{"lib.go": "package lib\n\nconst X = 1\n"}, Tags: []string{"v1.2.3", "release-2023", "v1.3.0-beta"}},
+		},
+	}
+}
+
+// withRelease adds a commit tagged with tag to the repository
+func withRelease(repo testenv.Repo, tag string) testenv.Repo {
+	repo.Commits = append(slices.Clone(repo.Commits), testenv.Commit{
+		Files: map[string]string{"release.go": "package " + repo.Name + "\n\n// " + tag + "\n"},
+		Tags:  []string{tag},
+	})
+	return repo
+}
+
+// unavailable lets the git requests of the repository fail, so that it can not be checked
+func unavailable(repo testenv.Repo) testenv.Repo {
+	repo.Unavailable = true
+	return repo
+}
+
+// startOrg serves the repositories as org "org"
+func startOrg(t *testing.T, repos ...testenv.Repo) *testenv.Env {
+	return testenv.Start(t, testenv.Description{
+		Orgs:   []testenv.Org{{Name: "org", Repos: repos}},
+		GoTags: testGoTags,
+	})
+}
+
+func testConfig(env *testenv.Env) MopherConfig {
+	return MopherConfig{
+		Org:            "org",
+		GithubApiUrl:   env.ApiUrl,
+		GithubRawUrl:   env.RawUrl,
+		GithubHost:     GithubUrl,
+		DockerhubUrl:   env.DockerhubUrl,
+		MaxConn:        4,
+		OutputTemplate: "{{.Output}}",
+		OutputEncode:   EncodingText,
+		WarnUnsyncDev:  true,
+		WarnGoVersion:  true,
+	}
+}
+
+// startEncodingTestEnv starts a fake forge with a finding of every kind
+func startEncodingTestEnv(t *testing.T) *testenv.Env {
+	return testenv.Start(t, testenv.Description{
+		Orgs: []testenv.Org{{Name: "org", Repos: []testenv.Repo{
+			libRepo(),
+			goRepo("a", goMod("github.com/org/a", "1.21.3", "github.com/org/lib v1.0.0")),
+			goRepo("other", goMod("github.com/other/b", "1.99")),
+			goRepo("broken", "module github.com/org/broken\n\nrequire (\n"),
+			{
+				Name: "multi",
+				Commits: []testenv.Commit{{
+					Files: map[string]string{
+						"go.mod":     goMod("github.com/org/multi", "1.99", "github.com/org/multi/api v1.3.0", "github.com/org/lib v1.2.2"),
+						"api/go.mod": goMod("github.com/org/multi/api", "1.99"),
+					},
+					Tags: []string{"v0.1.0", "api/v1.3.0", "api/v1.4.0"},
+				}},
+				DevBranch:  true,
+				DevCommits: []testenv.Commit{{Files: map[string]string{"dev.go": "package multi\n"}}},
+			},
+		}}},
+		GoTags: testGoTags,
+	})
+}
+
+// replaceServerUrl replaces the random port of the test server for stable golden files
+func replaceServerUrl(env *testenv.Env, output string) string {
+	output = strings.ReplaceAll(output, env.Server.URL, "https://forge.example")
+	return strings.ReplaceAll(output, env.Host(), "forge.example")
+}
+
+func runMopher(t *testing.T, config MopherConfig) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	config.Writer = buf
+	err := Mopher(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// readTestFile returns the content of file or "" if it does not exist
+func readTestFile(t *testing.T, file string) string {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func checkGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+	file := filepath.Join("testdata", "golden", name+".txt")
+	if *updateGolden {
+		err := os.MkdirAll(filepath.Dir(file), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(file, []byte(actual), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err, "(run 'go test ./pkg -update' to create golden files)")
+	}
+	if string(expected) != actual {
+		t.Errorf("output differs from %v\nexpected:\n%v\nactual:\n%v", file, string(expected), actual)
+	}
+}
+
+func TestReportSections(t *testing.T) {
+	cases := []struct {
+		name   string
+		repos  []testenv.Repo
+		config func(config MopherConfig) MopherConfig
+	}{
+		{
+			name: "no_warnings",
+			repos: []testenv.Repo{
+				libRepo(),
+				goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.3")),
+				{Name: "docs", Language: "Python", Commits: []testenv.Commit{{Files: map[string]string{"go.mod": goMod("github.com/org/docs", "1.1")}}}},
+				{Name: "old", Archived: true, Commits: []testenv.Commit{{Files: map[string]string{"go.mod": goMod("github.com/org/old", "1.1", "github.com/org/lib v1.0.0")}}}},
+				{Name: "empty"},
+			},
+		},
+		{
+			name: "wrong_module_name",
+			repos: []testenv.Repo{
+				goRepo("a", goMod("github.com/org/a", "1.99")),
+				goRepo("b", goMod("github.com/other/b", "1.99")),
+				goRepo("c", goMod("example.com/c", "1.99")),
+			},
+		},
+		{
+			name: "go_version",
+			repos: []testenv.Repo{
+				goRepo("a", goMod("github.com/org/a", "1.99")),
+				goRepo("b", goMod("github.com/org/b", "1.21.3")),
+				goRepo("c", goMod("github.com/org/c", "")),
+			},
+		},
+		{
+			name: "unsynced_dev",
+			repos: []testenv.Repo{
+				{
+					Name:      "synced",
+					Commits:   []testenv.Commit{{Files: map[string]string{"go.mod": goMod("github.com/org/synced", "1.99")}}},
+					DevBranch: true,
+				},
+				{
+					Name:       "unsynced",
+					Commits:    []testenv.Commit{{Files: map[string]string{"go.mod": goMod("github.com/org/unsynced", "1.99")}}},
+					DevBranch:  true,
+					DevCommits: []testenv.Commit{{Files: map[string]string{"dev.go": "package unsynced\n"}}},
+				},
+				{
+					Name:          "master",
+					DefaultBranch: "master",
+					Commits:       []testenv.Commit{{Files: map[string]string{"go.mod": goMod("github.com/org/master", "1.99")}}},
+					DevBranch:     true,
+					DevCommits:    []testenv.Commit{{Files: map[string]string{"dev.go": "package master\n"}}},
+				},
+			},
+		},
+		{
+			name: "outdated_dependencies",
+			repos: []testenv.Repo{
+				libRepo(),
+				goRepo("patch", goMod("github.com/org/patch", "1.99", "github.com/org/lib v1.2.2")),
+				goRepo("minor", goMod("github.com/org/minor", "1.99", "github.com/org/lib v1.0.0")),
+				goRepo("pseudo", goMod("github.com/org/pseudo", "1.99", "github.com/org/lib v0.0.0-20200101000000-abcdefabcdef")),
+				goRepo("ahead", goMod("github.com/org/ahead", "1.99", "github.com/org/lib v1.3.0")),
+				goRepo("current", goMod("github.com/org/current", "1.99", "github.com/org/lib v1.2.3")),
+				{
+					Name: "multi",
+					Commits: []testenv.Commit{{
+						Files: map[string]string{
+							"go.mod":     goMod("github.com/org/multi", "1.99", "github.com/org/multi/api v1.3.0"),
+							"api/go.mod": goMod("github.com/org/multi/api", "1.99"),
+						},
+						Tags: []string{"v0.1.0", "api/v1.3.0", "api/v1.4.0"},
+					}},
+				},
+				goRepo("v2", goMod("github.com/org/v2/v2", "1.99"), "v1.9.0", "v2.1.0", "v3.0.0"),
+				goRepo("v2user", goMod("github.com/org/v2user", "1.99", "github.com/org/v2/v2 v2.0.0")),
+			},
+		},
+		{
+			name: "min_lag",
+			repos: []testenv.Repo{
+				libRepo(),
+				goRepo("patch", goMod("github.com/org/patch", "1.99", "github.com/org/lib v1.2.2")),
+				goRepo("minor", goMod("github.com/org/minor", "1.99", "github.com/org/lib v1.0.0")),
+			},
+			config: func(config MopherConfig) MopherConfig {
+				config.MinLag = LagBehindMinor
+				return config
+			},
+		},
+		{
+			name: "dependents",
+			repos: []testenv.Repo{
+				libRepo(),
+				goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.3")),
+				goRepo("b", goMod("github.com/org/b", "1.99", "github.com/org/lib v1.0.0")),
+				goRepo("c", goMod("github.com/org/c", "1.99")),
+			},
+			config: func(config MopherConfig) MopherConfig {
+				config.Dep = "github.com/org/lib"
+				return config
+			},
+		},
+		{
+			name: "no_dependents",
+			repos: []testenv.Repo{
+				libRepo(),
+			},
+			config: func(config MopherConfig) MopherConfig {
+				config.Dep = "github.com/org/lib"
+				return config
+			},
+		},
+		{
+			name: "repo_errors",
+			repos: []testenv.Repo{
+				goRepo("a", goMod("github.com/org/a", "1.99")),
+				goRepo("broken", "module github.com/org/broken\n\nrequire (\n"),
+				goRepo("nomodule", "go 1.99\n"),
+			},
+		},
+		{
+			name: "broken_submodule",
+			repos: []testenv.Repo{
+				{
+					Name: "multi",
+					Commits: []testenv.Commit{{Files: map[string]string{
+						"go.mod":     goMod("github.com/org/multi", "1.99", "github.com/org/multi/api v1.3.0"),
+						"api/go.mod": goMod("github.com/org/multi/api", "1.99"),
+						"cmd/go.mod": "module github.com/org/multi/cmd\n\nrequire (\n",
+					}, Tags: []string{"api/v1.3.0", "api/v1.4.0"}}},
+				},
+			},
+		},
+		{
+			name: "update_order",
+			repos: []testenv.Repo{
+				libRepo(),
+				goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.0.0")),
+				goRepo("b", goMod("github.com/org/b", "1.99", "github.com/org/a v0.0.0-20200101000000-abcdefabcdef", "github.com/org/lib v1.0.0")),
+				goRepo("c", goMod("github.com/org/c", "1.99", "github.com/org/b v0.0.0-20200101000000-abcdefabcdef")),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env := testenv.Start(t, testenv.Description{
+				Orgs:   []testenv.Org{{Name: "org", Repos: c.repos}},
+				GoTags: testGoTags,
+			})
+			config := testConfig(env)
+			if c.config != nil {
+				config = c.config(config)
+			}
+			checkGolden(t, c.name, runMopher(t, config))
+		})
+	}
+}
+
+func TestTimeTravel(t *testing.T) {
+	env := testenv.Start(t, testenv.Description{
+		Orgs: []testenv.Org{{Name: "org", Repos: []testenv.Repo{
+			libRepo(),
+			{
+				Name: "a",
+				Commits: []testenv.Commit{
+					{Files: map[string]string{"go.mod": goMod("github.com/org/a", "1.99", "github.com/org/lib v1.0.0")}},
+					{Files: map[string]string{"a.go": "package a\n"}},
+					{Files: map[string]string{"go.mod": goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0")}},
+				},
+			},
+		}}},
+		GoTags: testGoTags,
+	})
+
+	config := testConfig(env)
+	config.At = testenv.CommitTime.Add(30 * time.Minute) //first commits, only v1.0.0 exists
+	output := runMopher(t, config)
+	if strings.Contains(output, "github.com/org/lib version") {
+		t.Error("unexpected outdated dependency before v1.2.0 was tagged\n", output)
+	}
+
+	config.At = testenv.CommitTime.Add(90 * time.Minute) //a still uses v1.0.0, lib is tagged v1.2.0
+	output = runMopher(t, config)
+	if !strings.Contains(output, "v1.0.0 github.com/org/a (behind-minor)") {
+		t.Error("missing behind-minor usage of v1.0.0\n", output)
+	}
+
+	config.At = time.Time{}
+	output = runMopher(t, config)
+	if !strings.Contains(output, "v1.2.0 github.com/org/a (behind-patch)") {
+		t.Error("missing behind-patch usage of v1.2.0\n", output)
+	}
+}
@@ -17,192 +17,179 @@
 package pkg
 
 import (
-	"fmt"
-	"github.com/google/go-github/v54/github"
 	"golang.org/x/mod/modfile"
-	"io"
 	"os"
 	"slices"
 	"sort"
 	"strings"
+	"time"
 )
 
 type Parsed struct {
-	Repos   []*github.Repository
-	Modules map[string]*modfile.File
-	Inverse map[string][]InverseIndexModRef
-	Latest  map[string]LatestCommitInfo
-	org     string
-	output  io.Writer
+	Repos             []Repository
+	Modules           map[string]*modfile.File
+	ModFiles          map[string][]byte //raw go.mod content, indexed by module name
+	Inverse           map[string][]InverseIndexModRef
+	Latest            map[string]LatestCommitInfo
+	Sources           map[string]ModuleSource
+	Errors            []RepoError //repositories that could not be checked
+	LatestGoVersion   string      //newest go version (major.minor), only loaded if MopherConfig.WarnGoVersion is set
+	org               string
+	host              string
+	includePrerelease bool
+	scannedAt         time.Time //start of the scan, kept in snapshots
 }
 
 type InverseIndexModRef struct {
-	UsesVersion     string
+	UsesVersion     string //commit hash for pseudo-versions, otherwise RawVersion
+	RawVersion      string //version as written in go.mod
 	UserModule      string
 	SemanticVersion bool
+	Indirect        bool
 }
 
 type LatestCommitInfo struct {
-	MainHash  string
-	DevHash   string
-	LatestTag string
+	MainHash  string `json:"main_hash"`
+	DevHash   string `json:"dev_hash,omitempty"`
+	LatestTag string `json:"latest_tag,omitempty"`
 }
 
-func (this *Parsed) StoreGraph(outputFile string, verbose bool) error {
-	text, err := this.generatePlantuml(verbose)
-	if err != nil {
-		return err
-	}
-	file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	_, err = file.Write([]byte(text))
-	if err != nil {
-		file.Close()
-		return err
-	}
-	return file.Close()
+type ModuleSource struct {
+	Repo  string `json:"repo"`            //full name of the repository containing the module
+	Url   string `json:"url"`             //html url of the repository
+	Path  string `json:"path"`            //location of the go.mod file in the repository
+	Forge string `json:"forge,omitempty"` //forge of the repository, decides the url scheme of links
 }
 
-func (this *Parsed) PrintDependents(dep string) error {
-	list := this.Inverse[dep]
-	if len(list) > 0 {
-		_, err := fmt.Fprintf(this.output, "\n\n%v is used by the following repositories (sorted by usage-version)\n", dep)
-		if err != nil {
-			return err
-		}
-		slices.SortFunc(list, func(a, b InverseIndexModRef) int {
-			return strings.Compare(a.UserModule, b.UserModule)
-		})
-		slices.SortFunc(list, func(a, b InverseIndexModRef) int {
-			return strings.Compare(a.UsesVersion, b.UsesVersion)
-		})
-		for _, ref := range list {
-			fmt.Println(ref.UserModule, ref.UsesVersion)
-		}
-	} else {
-		_, err := fmt.Fprintf(this.output, "\n\n%v is used by no %v repository as dependency\n", dep, this.org)
-		if err != nil {
-			return err
+// getModulesOfFailedRepos returns the modules of the previous run that are missing in the current run, because their repository could not be checked.
+// previous and current map module names to repositories, failed lists the repositories that could not be checked in the current run.
+// diff, distinct state and history carry over the results of these modules, so that a temporary error looks neither like removed modules nor like resolved findings
+func getModulesOfFailedRepos(previous map[string]string, current map[string]string, failed []string) map[string]string {
+	result := map[string]string{}
+	for module, repo := range previous {
+		if _, ok := current[module]; ok || repo == "" || !slices.Contains(failed, repo) {
+			continue
 		}
+		result[module] = repo
 	}
-
-	return nil
+	return result
 }
 
-func (this *Parsed) PrintWarnings(warnUnsyncDev bool, warnGoVersion bool) error {
-	updateOrderFilter := map[string]bool{}
-
-	deprecated, err := this.PrintWrongModuleNameWarnings()
-	if err != nil {
-		return err
+// keepFindingsOfModules adds the previous findings of the modules or dependencies in kept to current
+func keepFindingsOfModules(current []Finding, previous []Finding, kept map[string]string) []Finding {
+	result := slices.Clone(current)
+	keys := map[string]bool{}
+	for _, f := range result {
+		keys[f.Key()] = true
+	}
+	for _, f := range previous {
+		_, module := kept[f.Module]
+		_, dependency := kept[f.Dependency]
+		if keys[f.Key()] || (!module && !dependency) {
+			continue
+		}
+		result = append(result, f)
+		keys[f.Key()] = true
 	}
-	for _, d := range deprecated {
-		updateOrderFilter[d] = true
+	return result
+}
+
+func getModuleRepos(sources map[string]ModuleSource) map[string]string {
+	result := map[string]string{}
+	for name, source := range sources {
+		result[name] = source.Repo
 	}
+	return result
+}
 
-	if warnGoVersion {
-		deprecated, err = this.PrintGoVersionWarnings()
-		if err != nil {
-			return err
-		}
-		for _, d := range deprecated {
-			updateOrderFilter[d] = true
+func getErrorRepos(errs []RepoError) (result []string) {
+	for _, e := range errs {
+		if !slices.Contains(result, e.Repo) {
+			result = append(result, e.Repo)
 		}
 	}
+	return result
+}
 
-	if warnUnsyncDev {
-		deprecated, err = this.PrintUnsincBranches()
-		if err != nil {
-			return err
-		}
-		for _, d := range deprecated {
-			updateOrderFilter[d] = true
-		}
+// addModulesOfFailedRepos adds the modules of repositories that could not be checked from previous (see getModulesOfFailedRepos)
+func (this *Parsed) addModulesOfFailedRepos(previous *Parsed) {
+	kept := getModulesOfFailedRepos(getModuleRepos(previous.Sources), getModuleRepos(this.Sources), getErrorRepos(this.Errors))
+	if len(kept) == 0 {
+		return
+	}
+	for name := range kept {
+		this.Modules[name] = previous.Modules[name]
+		this.ModFiles[name] = previous.ModFiles[name]
+		this.Latest[name] = previous.Latest[name]
+		this.Sources[name] = previous.Sources[name]
 	}
+	this.Inverse = map[string][]InverseIndexModRef{}
+	this.buildInverseIndex()
+}
 
-	deprecated, err = this.PrintDependencyVersionWarnings()
+func (this *Parsed) StoreGraph(outputFile string, verbose bool) error {
+	text, err := this.generatePlantuml(verbose)
 	if err != nil {
 		return err
 	}
-	for _, d := range deprecated {
-		updateOrderFilter[d] = true
+	file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write([]byte(text))
+	if err != nil {
+		file.Close()
+		return err
 	}
-	err = this.PrintUpdateOrder(updateOrderFilter)
-	return nil
+	return file.Close()
 }
 
-func (this *Parsed) PrintWrongModuleNameWarnings() (deprecated []string, err error) {
-	invalidNames := []string{}
+func (this *Parsed) GetWrongModuleNameFindings() (result []Finding) {
 	for name, _ := range this.Modules {
-		if !strings.HasPrefix(name, GithubUrl+"/"+this.org) {
-			invalidNames = append(invalidNames, name)
+		if !strings.HasPrefix(name, this.host+"/"+this.org) {
+			result = append(result, Finding{
+				Kind:   FindingWrongModuleName,
+				Module: name,
+			})
 		}
 	}
-	if len(invalidNames) > 0 {
-		_, err = fmt.Fprintln(this.output, "\n\nfound unexpected module names:")
-		if err != nil {
-			return deprecated, err
-		}
-		for _, name := range invalidNames {
-			_, err = fmt.Fprintln(this.output, name)
-			if err != nil {
-				return deprecated, err
-			}
-			deprecated = append(deprecated, name)
-		}
-	}
-	return deprecated, nil
+	slices.SortFunc(result, func(a, b Finding) int {
+		return strings.Compare(a.Module, b.Module)
+	})
+	return result
 }
 
-func (this *Parsed) PrintUnsincBranches() (deprecated []string, err error) {
+func (this *Parsed) GetUnsyncBranchFindings() (result []Finding) {
 	unsyncRepos := []string{}
 	for module, commitInfo := range this.Latest {
 		if commitInfo.DevHash != "" && commitInfo.DevHash != commitInfo.MainHash {
 			unsyncRepos = append(unsyncRepos, module)
 		}
 	}
-	if len(unsyncRepos) > 0 {
-		sort.Strings(unsyncRepos)
-		_, err = fmt.Fprintln(this.output, "\n\nfound repositories where master/main and dev branches are not synced:")
-		if err != nil {
-			return deprecated, err
-		}
-		for _, name := range unsyncRepos {
-			_, err = fmt.Fprintln(this.output, name)
-			if err != nil {
-				return deprecated, err
-			}
-			deprecated = append(deprecated, name)
-		}
+	sort.Strings(unsyncRepos)
+	for _, name := range unsyncRepos {
+		result = append(result, Finding{
+			Kind:            FindingUnsyncedDevBranch,
+			Module:          name,
+			UsedVersion:     this.Latest[name].DevHash,
+			ExpectedVersion: this.Latest[name].MainHash,
+		})
 	}
-	return deprecated, nil
+	return result
 }
 
-func (this *Parsed) PrintUpdateOrder(filter map[string]bool) error {
+func (this *Parsed) GetFilteredUpdateOrder(filter map[string]bool) (result []string, err error) {
 	order, err := this.GetRecommendedUpdateOrder()
 	if err != nil {
-		return err
-	}
-	_, err = fmt.Fprintf(this.output, "\n\nrecommended update order:\n")
-	if err != nil {
-		return err
+		return result, err
 	}
 	for _, e := range order {
 		if this.toBeUpdated(filter, e) {
 			filter[e] = true
-			_, err = fmt.Fprintln(this.output, e)
-			if err != nil {
-				return err
-			}
+			result = append(result, e)
 		}
 	}
-	return nil
-}
-
-func (this *Parsed) SetOutput(output io.Writer) {
-	this.output = output
+	return result, nil
 }
 
 type VersionUsageRef struct {
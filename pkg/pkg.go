@@ -19,7 +19,6 @@ package pkg
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	cron "github.com/robfig/cron/v3"
@@ -30,29 +29,60 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type MopherConfig struct {
-	Writer         io.Writer
-	Output         string //creates writer if none is set
-	Org            string
-	MaxConn        int
-	Graph          string
-	Verbose        bool
-	Dep            string
-	WarnUnsyncDev  bool
-	WarnGoVersion  bool
-	PreOutputHook  PreOutputHookFunction
-	OutputTemplate string
-	OutputEncode   string
+	Writer               io.Writer
+	Output               string //creates writer if none is set
+	Org                  string //github org or gitlab group
+	Forge                string //github (default), gitlab, gitea (also for forgejo) or local
+	GithubToken          string //optional, used for api, raw content and git requests
+	GithubApiUrl         string //optional, GitHub Enterprise api url (e.g. https://github.example.com/api/v3/)
+	GithubRawUrl         string //optional, defaults to https://raw.githubusercontent.com/ or <host>/raw/ for GitHub Enterprise
+	GithubHost           string //optional, expected module name host for GitHub Enterprise, defaults to the host of GithubApiUrl
+	GitlabToken          string //optional, used for api and git requests
+	GitlabUrl            string //base url of the gitlab instance (e.g. https://gitlab.example.com)
+	GiteaToken           string //optional, used for api and git requests
+	GiteaUrl             string //base url of the gitea/forgejo instance (e.g. https://gitea.example.com)
+	LocalDir             string //directory containing checkouts of the org repositories, used by the local forge
+	LocalHost            string //expected module name host for the local forge, defaults to github.com
+	DockerhubUrl         string //optional, docker hub golang tags url used to find the latest go version, defaults to DockerhubGolangTagsUrl
+	MaxConn              int
+	Graph                string
+	Verbose              bool
+	Dep                  string
+	WarnUnsyncDev        bool
+	WarnGoVersion        bool
+	MinLag               Lag           //optional, see ReportOptions.MinLag
+	IncludePrerelease    bool          //use pre-release tags (e.g. v2.0.0-beta) as latest tag
+	FailOnRepoErrors     bool          //abort if a repository could not be checked, instead of listing it in the report
+	Timeout              time.Duration //optional, max duration of a whole run
+	RequestTimeout       time.Duration //optional, max duration of a single http request or git ls-remote
+	MaxRetries           int           //retries of http requests on network errors, 5xx responses and rate limits
+	CacheDir             string        //optional, directory for cached http responses
+	StateFile            string        //optional, file to persist scan results; repositories not pushed since the last scan reuse these results
+	SaveSnapshot         string        //optional, file to store a Snapshot of the loaded state
+	LoadSnapshot         string        //optional, Snapshot file used instead of scanning the org; no network access is needed
+	DiffFile             string        //optional, Snapshot file of the previous run; only the Diff to this run is written and the file is updated
+	DistinctStateFile    string        //optional, file to persist findings; only findings that are new or resolved since the previous run are written
+	DigestInterval       time.Duration //optional, with DistinctStateFile: writes the full report if this duration has passed since the last full report
+	HistoryFile          string        //optional, every run appends a HistoryEntry to this file; read by MopherHistory
+	UsageAllDependencies bool          //MopherUsage lists non-org dependencies too
+	At                   time.Time     //optional, scans the org as it was at this time (repositories are cloned to read their history)
+	PreOutputHook        PreOutputHookFunction
+	OutputTemplate       string
+	OutputEncode         string
 }
 
 type PreOutputHookFunction = func(warnings string) (changedWarnings string, shouldBeWritenToOutput bool)
 
+// CronMopher runs Mopher by the cron schedule and blocks until ctx is done.
+// a running scan is canceled with ctx and CronMopher returns after it has stopped.
 func CronMopher(ctx context.Context, cronString string, config MopherConfig) error {
 	c := cron.New()
 	_, err := c.AddFunc(cronString, func() {
-		err := Mopher(config)
+		err := Mopher(ctx, config)
 		if err != nil {
 			log.Println("ERROR:", err)
 		}
@@ -62,108 +92,158 @@ func CronMopher(ctx context.Context, cronString string, config MopherConfig) err
 	}
 
 	c.Start()
-	go func() {
-		<-ctx.Done()
-		c.Stop()
-	}()
+	<-ctx.Done()
+	<-c.Stop().Done()
 	return nil
 }
 
-func Mopher(config MopherConfig) error {
-	if config.Org == "" {
+func Mopher(ctx context.Context, config MopherConfig) error {
+	if config.Org == "" && config.LoadSnapshot == "" {
 		return errors.New("missing org input")
 	}
 
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
 	tmpl, err := template.New("templ").Parse(config.OutputTemplate)
 	if err != nil {
 		return err
 	}
 
-	parsed, err := LoadOrg(config.Org, config.MaxConn)
+	//fail before the scan instead of after the first change
+	if (config.DiffFile != "" || config.DistinctStateFile != "") && !isDiffEncoding(config.OutputEncode) {
+		return fmt.Errorf("unexpected output encoding for diffs: %v", config.OutputEncode)
+	}
+
+	var previous *Parsed
+	if config.DiffFile != "" {
+		previous, err = loadDiffBase(config.DiffFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	parsed, err := loadParsed(ctx, config)
 	if err != nil {
 		return err
 	}
 
-	var writer strings.Builder
-	parsed.SetOutput(&writer)
-	defer writer.Reset()
-
 	if config.Graph != "" {
 		err = parsed.StoreGraph(config.Graph, config.Verbose)
 		if err != nil {
 			return err
 		}
 	}
-	if config.Dep != "" {
-		err = parsed.PrintDependents(config.Dep)
+
+	report, err := parsed.GetReport(config.getReportOptions())
+	if err != nil {
+		return err
+	}
+
+	if config.HistoryFile != "" {
+		entry := parsed.GetHistoryEntry(report)
+		if !config.At.IsZero() {
+			entry.Time = config.At //allows to backfill the history with time travel scans
+		}
+		err = AppendHistory(config.HistoryFile, entry)
 		if err != nil {
 			return err
 		}
 	}
-	err = parsed.PrintWarnings(config.WarnUnsyncDev, config.WarnGoVersion)
+
+	if config.DiffFile != "" {
+		return outputDiff(ctx, config, tmpl, previous, parsed)
+	}
+	if config.DistinctStateFile != "" {
+		return outputFindingChanges(ctx, config, tmpl, report)
+	}
+
+	warnings, err := EncodeReport(report, config.OutputEncode)
 	if err != nil {
 		return err
 	}
+	return writeOutput(ctx, config, tmpl, warnings, map[string]interface{}{"Report": report})
+}
 
-	warnings := writer.String()
-
+// writeOutput applies config.PreOutputHook and the template to output and writes the result to the configured output.
+// data is passed to the template with output as additional "Output" field
+func writeOutput(ctx context.Context, config MopherConfig, tmpl *template.Template, output string, data map[string]interface{}) (err error) {
 	write := true
 	if config.PreOutputHook != nil {
-		warnings, write = config.PreOutputHook(warnings)
+		output, write = config.PreOutputHook(output)
 	}
-
-	if write {
-		var templateInput string
-		switch config.OutputEncode {
-		case "application/json":
-			temp, err := json.Marshal(strings.TrimSpace(warnings))
-			if err != nil {
-				return err
-			}
-			templateInput = string(temp)
-		case "plain/text":
-			templateInput = warnings
-		default:
-			templateInput = warnings
-		}
-
-		templateOutBuff := strings.Builder{}
-		err = tmpl.Execute(&templateOutBuff, map[string]interface{}{"Output": templateInput})
+	if !write {
+		return nil
+	}
+	data["Output"] = output
+	templateOutBuff := strings.Builder{}
+	err = tmpl.Execute(&templateOutBuff, data)
+	if err != nil {
+		return err
+	}
+	templateOutput := templateOutBuff.String()
+	switch {
+	case config.Writer != nil:
+		_, err = config.Writer.Write([]byte(templateOutput))
+	case strings.HasPrefix(config.Output, "http://") || strings.HasPrefix(config.Output, "https://"):
+		err = SendHttpPost(ctx, config.Output, templateOutput)
+	case config.Output == "":
+		fmt.Print(templateOutput)
+	default:
+		var file io.WriteCloser
+		file, err = os.OpenFile(config.Output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 		if err != nil {
-			return err
+			return fmt.Errorf("unable to open output file %v %w", config.Output, err)
 		}
-		templateOutput := templateOutBuff.String()
-		switch {
-		case config.Writer != nil:
-			_, err = config.Writer.Write([]byte(templateOutput))
-		case strings.HasPrefix(config.Output, "http://") || strings.HasPrefix(config.Output, "https://"):
-			err = SendHttpPost(config.Output, templateOutput)
-		case config.Output == "":
-			fmt.Print(templateOutput)
-		default:
-			var file io.WriteCloser
-			file, err = os.OpenFile(config.Output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-			if err != nil {
-				return fmt.Errorf("unable to open output file %v %w", config.Output, err)
-			}
-			defer func() {
-				err := file.Close()
-				if err != nil {
-					fmt.Println("unable to close output file", config.Output, err)
-				}
-			}()
-			_, err = file.Write([]byte(templateOutput))
+		defer func() {
+			err := file.Close()
 			if err != nil {
-				return fmt.Errorf("unable to open write to output file %v %w", config.Output, err)
+				fmt.Println("unable to close output file", config.Output, err)
 			}
+		}()
+		_, err = file.Write([]byte(templateOutput))
+		if err != nil {
+			return fmt.Errorf("unable to open write to output file %v %w", config.Output, err)
 		}
 	}
+	return err
+}
 
-	return nil
+func (this MopherConfig) getReportOptions() ReportOptions {
+	return ReportOptions{
+		Dependency:    this.Dep,
+		WarnUnsyncDev: this.WarnUnsyncDev,
+		WarnGoVersion: this.WarnGoVersion,
+		MinLag:        this.MinLag,
+	}
 }
 
-func SendHttpPost(endpoint string, message string) error {
-	resp, err := http.Post(endpoint, "application/json", bytes.NewBufferString(message))
+// loadParsed scans the org or loads config.LoadSnapshot and stores the result in config.SaveSnapshot
+func loadParsed(ctx context.Context, config MopherConfig) (parsed *Parsed, err error) {
+	if config.LoadSnapshot != "" {
+		parsed, err = LoadSnapshotFile(config.LoadSnapshot)
+	} else {
+		parsed, err = LoadOrg(ctx, config)
+	}
+	if err != nil {
+		return parsed, err
+	}
+	if config.SaveSnapshot != "" {
+		err = WriteSnapshotFile(config.SaveSnapshot, parsed.GetSnapshot())
+	}
+	return parsed, err
+}
+
+func SendHttpPost(ctx context.Context, endpoint string, message string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"testing"
+	"time"
+)
+
+// startSlowOrg serves an org whose responses take longer than any test
+func startSlowOrg(t *testing.T) *testenv.Env {
+	return testenv.Start(t, testenv.Description{
+		Orgs:    []testenv.Org{{Name: "org", Repos: []testenv.Repo{libRepo()}}},
+		GoTags:  testGoTags,
+		Latency: time.Hour,
+	})
+}
+
+func TestMopherCancel(t *testing.T) {
+	cases := []struct {
+		name     string
+		run      func(config MopherConfig) error
+		expected error
+	}{
+		{
+			name: "cancel",
+			run: func(config MopherConfig) error {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return Mopher(ctx, config)
+			},
+			expected: context.Canceled,
+		},
+		{
+			name: "timeout",
+			run: func(config MopherConfig) error {
+				config.Timeout = 50 * time.Millisecond
+				return Mopher(context.Background(), config)
+			},
+			expected: context.DeadlineExceeded,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := testConfig(startSlowOrg(t))
+			start := time.Now()
+			err := c.run(config)
+			if !errors.Is(err, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("scan stopped after %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestCronMopherStopsRunningScan(t *testing.T) {
+	env := startSlowOrg(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- CronMopher(ctx, "@every 10ms", testConfig(env))
+	}()
+	time.Sleep(100 * time.Millisecond) //let the cron start a scan that waits for the forge
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("cron did not stop the running scan")
+	}
+}
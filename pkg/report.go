@@ -0,0 +1,211 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type FindingKind string
+
+const (
+	FindingWrongModuleName    FindingKind = "wrong_module_name"
+	FindingOutdatedGoVersion  FindingKind = "outdated_go_version"
+	FindingUnsyncedDevBranch  FindingKind = "unsynced_dev_branch"
+	FindingOutdatedDependency FindingKind = "outdated_dependency"
+)
+
+type Finding struct {
+	Kind                FindingKind `json:"kind"`
+	Module              string      `json:"module"`
+	Dependency          string      `json:"dependency,omitempty"`
+	UsedVersion         string      `json:"used_version,omitempty"`
+	ExpectedVersion     string      `json:"expected_version,omitempty"`
+	Lag                 Lag         `json:"lag,omitempty"` //only set for outdated_dependency findings
+	RepoUrl             string      `json:"repo_url,omitempty"`
+	Line                int         `json:"line,omitempty"`                  //line in the go.mod file of Module: the require line of outdated dependencies, the go directive of outdated go versions, otherwise the module directive
+	UpdateOrderPosition int         `json:"update_order_position,omitempty"` //1-based position of Module in Report.UpdateOrder, 0 if the module is not listed
+}
+
+type DependentRef struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+// DependencyEdge is a dependency between two org modules
+type DependencyEdge struct {
+	Module     string `json:"module"`
+	Dependency string `json:"dependency"`
+	Version    string `json:"version"`
+}
+
+type Report struct {
+	Org         string                      `json:"org"`
+	Dependency  string                      `json:"dependency,omitempty"`
+	Dependents  []DependentRef              `json:"dependents,omitempty"`
+	Latest      map[string]LatestCommitInfo `json:"latest"`
+	Findings    []Finding                   `json:"findings"`
+	Errors      []RepoError                 `json:"errors,omitempty"` //repositories that could not be checked
+	UpdateOrder []string                    `json:"update_order"`
+	Sources     map[string]ModuleSource     `json:"sources"` //repositories of the org modules
+	Edges       []DependencyEdge            `json:"-"`       //dependencies between org modules, sorted by module and dependency; used by the html graph, not part of the json report
+}
+
+type ReportOptions struct {
+	Dependency    string //optional, lists the usages of this dependency in Report.Dependents
+	WarnUnsyncDev bool
+	WarnGoVersion bool
+	MinLag        Lag //optional, hides outdated dependencies with a known lag below this lag (e.g. behind-minor hides behind-patch)
+}
+
+func (this *Parsed) GetReport(options ReportOptions) (report Report, err error) {
+	report = Report{
+		Org:        this.org,
+		Dependency: options.Dependency,
+		Latest:     this.Latest,
+		Sources:    this.Sources,
+		Findings:   []Finding{},
+		Errors:     this.Errors,
+		Edges:      this.GetDependencyEdges(),
+	}
+	if options.Dependency != "" {
+		report.Dependents = this.GetDependents(options.Dependency)
+	}
+
+	report.Findings = append(report.Findings, this.GetWrongModuleNameFindings()...)
+	if options.WarnGoVersion {
+		report.Findings = append(report.Findings, this.GetGoVersionFindings()...)
+	}
+	if options.WarnUnsyncDev {
+		report.Findings = append(report.Findings, this.GetUnsyncBranchFindings()...)
+	}
+	for _, f := range this.GetDependencyVersionFindings() {
+		if options.MinLag == "" || f.Lag == LagUnknown || f.Lag.AtLeast(options.MinLag) {
+			report.Findings = append(report.Findings, f)
+		}
+	}
+
+	updateOrderFilter := map[string]bool{}
+	for _, f := range report.Findings {
+		updateOrderFilter[f.Module] = true
+	}
+	report.UpdateOrder, err = this.GetFilteredUpdateOrder(updateOrderFilter)
+	if err != nil {
+		return report, err
+	}
+
+	for i, f := range report.Findings {
+		report.Findings[i].RepoUrl = this.Sources[f.Module].Url
+		report.Findings[i].Line = this.getFindingLine(f)
+		if pos := slices.Index(report.UpdateOrder, f.Module); pos >= 0 {
+			report.Findings[i].UpdateOrderPosition = pos + 1
+		}
+	}
+	return report, nil
+}
+
+func (this *Parsed) getFindingLine(f Finding) int {
+	file, ok := this.Modules[f.Module]
+	if !ok || file.Module == nil || file.Module.Syntax == nil {
+		return 0
+	}
+	switch f.Kind {
+	case FindingOutdatedGoVersion:
+		if file.Go != nil && file.Go.Syntax != nil {
+			return file.Go.Syntax.Start.Line
+		}
+	case FindingOutdatedDependency:
+		for _, req := range file.Require {
+			if req.Mod.Path == f.Dependency && req.Syntax != nil {
+				return req.Syntax.Start.Line
+			}
+		}
+	}
+	return file.Module.Syntax.Start.Line
+}
+
+func (this *Parsed) GetDependents(dep string) (result []DependentRef) {
+	for _, ref := range this.Inverse[dep] {
+		result = append(result, DependentRef{
+			Module:  ref.UserModule,
+			Version: ref.UsesVersion,
+		})
+	}
+	slices.SortStableFunc(result, func(a, b DependentRef) int {
+		return strings.Compare(a.Module, b.Module)
+	})
+	slices.SortStableFunc(result, func(a, b DependentRef) int {
+		return compareVersions(a.Version, b.Version)
+	})
+	return result
+}
+
+func (this *Parsed) GetDependencyEdges() (result []DependencyEdge) {
+	result = []DependencyEdge{}
+	for dep, refs := range this.Inverse {
+		if _, ok := this.Modules[dep]; !ok {
+			continue
+		}
+		for _, ref := range refs {
+			result = append(result, DependencyEdge{
+				Module:     ref.UserModule,
+				Dependency: dep,
+				Version:    ref.UsesVersion,
+			})
+		}
+	}
+	slices.SortFunc(result, func(a, b DependencyEdge) int {
+		if c := strings.Compare(a.Module, b.Module); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Dependency, b.Dependency)
+	})
+	return result
+}
+
+// FindingsOfKind returns the findings of the given kind, preserving the report order
+func (this Report) FindingsOfKind(kind FindingKind) (result []Finding) {
+	for _, f := range this.Findings {
+		if f.Kind == kind {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Key identifies the finding independent of versions, so that it is stable between scans
+func (this Finding) Key() string {
+	return strings.Join([]string{string(this.Kind), this.Module, this.Dependency}, " ")
+}
+
+// String describes the finding in a single line
+func (this Finding) String() string {
+	switch this.Kind {
+	case FindingWrongModuleName:
+		return fmt.Sprintf("unexpected module name %v", this.Module)
+	case FindingOutdatedGoVersion:
+		return fmt.Sprintf("%v uses go version %v != %v", this.Module, this.UsedVersion, this.ExpectedVersion)
+	case FindingUnsyncedDevBranch:
+		return fmt.Sprintf("%v master/main and dev branches are not synced", this.Module)
+	case FindingOutdatedDependency:
+		return fmt.Sprintf("%v uses %v %v != %v (%v)", this.Module, this.Dependency, this.UsedVersion, this.ExpectedVersion, this.Lag)
+	default:
+		return fmt.Sprintf("%v %v %v", this.Kind, this.Module, this.Dependency)
+	}
+}
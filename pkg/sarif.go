@@ -0,0 +1,248 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+// SARIF 2.1.0 (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html), limited to the properties used by mopher
+
+const SarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool                     SarifTool                    `json:"tool"`
+	AutomationDetails        SarifAutomationDetails       `json:"automationDetails"`
+	VersionControlProvenance []SarifVersionControlDetails `json:"versionControlProvenance,omitempty"`
+	Invocations              []SarifInvocation            `json:"invocations"`
+	Results                  []SarifResult                `json:"results"`
+	Properties               map[string]string            `json:"properties,omitempty"`
+}
+
+type SarifTool struct {
+	Driver SarifToolComponent `json:"driver"`
+}
+
+type SarifToolComponent struct {
+	Name           string               `json:"name"`
+	InformationUri string               `json:"informationUri"`
+	Rules          []SarifReportingRule `json:"rules"`
+}
+
+type SarifReportingRule struct {
+	Id                   string                      `json:"id"`
+	Name                 string                      `json:"name"`
+	ShortDescription     SarifMessage                `json:"shortDescription"`
+	FullDescription      SarifMessage                `json:"fullDescription"`
+	DefaultConfiguration SarifReportingConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifReportingConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifAutomationDetails struct {
+	Id string `json:"id"`
+}
+
+type SarifVersionControlDetails struct {
+	RepositoryUri string `json:"repositoryUri"`
+}
+
+type SarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []SarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type SarifNotification struct {
+	Level   string       `json:"level"`
+	Message SarifMessage `json:"message"`
+}
+
+type SarifResult struct {
+	RuleId              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             SarifMessage      `json:"message"`
+	Locations           []SarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           *SarifRegion          `json:"region,omitempty"`
+}
+
+type SarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type SarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifRules defines a rule per finding kind; the rule id is the finding kind
+var sarifRules = []SarifReportingRule{
+	{
+		Id:                   string(FindingWrongModuleName),
+		Name:                 "WrongModuleName",
+		ShortDescription:     SarifMessage{Text: "module name does not match the org"},
+		FullDescription:      SarifMessage{Text: "the module directive of the go.mod file does not start with the expected org prefix; imports of this module by its org path fail"},
+		DefaultConfiguration: SarifReportingConfiguration{Level: "error"},
+	},
+	{
+		Id:                   string(FindingOutdatedGoVersion),
+		Name:                 "OutdatedGoVersion",
+		ShortDescription:     SarifMessage{Text: "go directive is outdated"},
+		FullDescription:      SarifMessage{Text: "the go directive of the go.mod file is older than the latest go version"},
+		DefaultConfiguration: SarifReportingConfiguration{Level: "warning"},
+	},
+	{
+		Id:                   string(FindingUnsyncedDevBranch),
+		Name:                 "UnsyncedDevBranch",
+		ShortDescription:     SarifMessage{Text: "dev branch is not synced"},
+		FullDescription:      SarifMessage{Text: "the dev branch and the master/main branch of the repository point to different commits"},
+		DefaultConfiguration: SarifReportingConfiguration{Level: "note"},
+	},
+	{
+		Id:                   string(FindingOutdatedDependency),
+		Name:                 "OutdatedOrgDependency",
+		ShortDescription:     SarifMessage{Text: "org dependency is outdated"},
+		FullDescription:      SarifMessage{Text: "a required org module is not used in its latest version; behind-major usages are errors, behind-patch usages are notes"},
+		DefaultConfiguration: SarifReportingConfiguration{Level: "warning"},
+	},
+}
+
+// WriteSarifReport writes the report as SARIF 2.1.0 log with one run per repository,
+// so that the results can be uploaded to the code-scanning dashboard of each repository
+func WriteSarifReport(out io.Writer, report Report) error {
+	sarif := SarifLog{
+		Schema:  SarifSchema,
+		Version: "2.1.0",
+		Runs:    []SarifRun{},
+	}
+	runs := map[string]*SarifRun{}
+	getRun := func(repo string, url string) *SarifRun {
+		run, ok := runs[repo]
+		if !ok {
+			run = &SarifRun{
+				Tool: SarifTool{Driver: SarifToolComponent{
+					Name:           "mopher",
+					InformationUri: "https://github.com/SENERGY-Platform/mopher",
+					Rules:          sarifRules,
+				}},
+				AutomationDetails: SarifAutomationDetails{Id: "mopher/" + repo + "/"},
+				Invocations:       []SarifInvocation{{ExecutionSuccessful: true}},
+				Results:           []SarifResult{},
+				Properties:        map[string]string{"repository": repo},
+			}
+			runs[repo] = run
+		}
+		if url != "" && run.VersionControlProvenance == nil {
+			run.VersionControlProvenance = []SarifVersionControlDetails{{RepositoryUri: url}}
+		}
+		return run
+	}
+
+	for _, f := range report.Findings {
+		source := report.Sources[f.Module]
+		run := getRun(source.Repo, f.RepoUrl)
+		run.Results = append(run.Results, getSarifResult(f, source))
+	}
+	for _, e := range report.Errors {
+		run := getRun(e.Repo, "")
+		run.Invocations[0].ExecutionSuccessful = false
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, SarifNotification{
+			Level:   "error",
+			Message: SarifMessage{Text: string(e.Phase) + ": " + e.Message},
+		})
+	}
+
+	for _, repo := range getSortedKeys(runs) {
+		sarif.Runs = append(sarif.Runs, *runs[repo])
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarif)
+}
+
+func getSarifResult(f Finding, source ModuleSource) SarifResult {
+	result := SarifResult{
+		RuleId: string(f.Kind),
+		RuleIndex: slices.IndexFunc(sarifRules, func(rule SarifReportingRule) bool {
+			return rule.Id == string(f.Kind)
+		}),
+		Level:               getSarifLevel(f),
+		Message:             SarifMessage{Text: f.String()},
+		PartialFingerprints: map[string]string{"mopherFindingKey/v1": f.Key()},
+		Properties:          map[string]string{"module": f.Module},
+	}
+	if f.Dependency != "" {
+		result.Properties["dependency"] = f.Dependency
+	}
+	if f.UsedVersion != "" {
+		result.Properties["usedVersion"] = f.UsedVersion
+	}
+	if f.ExpectedVersion != "" {
+		result.Properties["expectedVersion"] = f.ExpectedVersion
+	}
+	if f.Lag != "" {
+		result.Properties["lag"] = string(f.Lag)
+	}
+	if source.Path != "" {
+		location := SarifLocation{PhysicalLocation: SarifPhysicalLocation{ArtifactLocation: SarifArtifactLocation{Uri: source.Path}}}
+		if f.Line > 0 {
+			location.PhysicalLocation.Region = &SarifRegion{StartLine: f.Line}
+		}
+		result.Locations = []SarifLocation{location}
+	}
+	return result
+}
+
+func getSarifLevel(f Finding) string {
+	if f.Kind == FindingOutdatedDependency {
+		switch f.Lag {
+		case LagBehindMajor:
+			return "error"
+		case LagBehindPatch:
+			return "note"
+		}
+	}
+	for _, rule := range sarifRules {
+		if rule.Id == string(f.Kind) {
+			return rule.DefaultConfiguration.Level
+		}
+	}
+	return "warning"
+}
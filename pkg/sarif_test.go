@@ -0,0 +1,55 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSarifReport(t *testing.T) {
+	env := startEncodingTestEnv(t)
+	config := testConfig(env)
+	config.OutputEncode = EncodingSarif
+	output := runMopher(t, config)
+	checkGolden(t, "sarif", replaceServerUrl(env, output))
+
+	sarif := SarifLog{}
+	err := json.Unmarshal([]byte(output), &sarif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := map[string]int{}
+	for _, run := range sarif.Runs {
+		for _, result := range run.Results {
+			for _, location := range result.Locations {
+				lines[run.Properties["repository"]+"/"+location.PhysicalLocation.ArtifactLocation.Uri+" "+result.RuleId] = location.PhysicalLocation.Region.StartLine
+			}
+		}
+	}
+	expected := map[string]int{
+		"org/a/go.mod outdated_go_version":         3,
+		"org/a/go.mod outdated_dependency":         6,
+		"org/other/go.mod wrong_module_name":       1,
+		"org/multi/api/go.mod unsynced_dev_branch": 1,
+	}
+	for key, line := range expected {
+		if lines[key] != line {
+			t.Errorf("%v: expected line %v, got %v", key, line, lines[key])
+		}
+	}
+}
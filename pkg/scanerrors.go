@@ -0,0 +1,90 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type ScanPhase string
+
+const (
+	PhaseListing   ScanPhase = "listing"
+	PhaseModFetch  ScanPhase = "go.mod fetch"
+	PhaseParse     ScanPhase = "parse"
+	PhaseLsRemote  ScanPhase = "ls-remote"
+	PhaseUndefined ScanPhase = "unknown"
+)
+
+// RepoError describes why a repository could not be checked
+type RepoError struct {
+	Repo    string    `json:"repo"`
+	Phase   ScanPhase `json:"phase"`
+	Message string    `json:"error"`
+}
+
+func (this RepoError) String() string {
+	return fmt.Sprintf("%v (%v): %v", this.Repo, this.Phase, this.Message)
+}
+
+type phaseError struct {
+	phase ScanPhase
+	err   error
+}
+
+func (this *phaseError) Error() string {
+	return string(this.phase) + ": " + this.err.Error()
+}
+
+func (this *phaseError) Unwrap() error {
+	return this.err
+}
+
+func withPhase(phase ScanPhase, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &phaseError{phase: phase, err: err}
+}
+
+func newRepoError(repo string, err error) RepoError {
+	result := RepoError{Repo: repo, Phase: PhaseUndefined, Message: err.Error()}
+	var pErr *phaseError
+	if errors.As(err, &pErr) {
+		result.Phase = pErr.phase
+		result.Message = pErr.err.Error()
+	}
+	return result
+}
+
+func sortRepoErrors(list []RepoError) {
+	slices.SortFunc(list, func(a, b RepoError) int {
+		return strings.Compare(a.Repo, b.Repo)
+	})
+}
+
+// JoinRepoErrors converts the list into a single error or nil if the list is empty
+func JoinRepoErrors(list []RepoError) error {
+	errs := []error{}
+	for _, e := range list {
+		errs = append(errs, errors.New(e.String()))
+	}
+	return errors.Join(errs...)
+}
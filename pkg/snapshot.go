@@ -0,0 +1,134 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"os"
+	"time"
+)
+
+// SnapshotVersion is incremented on incompatible changes of the Snapshot format
+const SnapshotVersion = 1
+
+// Snapshot is the complete loaded state of a scan.
+// warnings, update order and graph can be computed from it without network access (see Snapshot.Parsed)
+type Snapshot struct {
+	Version           int                         `json:"version"`
+	CreatedAt         time.Time                   `json:"created_at"`
+	Org               string                      `json:"org"`
+	Host              string                      `json:"host"`
+	IncludePrerelease bool                        `json:"include_prerelease"`
+	LatestGoVersion   string                      `json:"latest_go_version,omitempty"`
+	Repos             []Repository                `json:"repos"`
+	ModFiles          map[string]string           `json:"mod_files"` //raw go.mod content, indexed by module name
+	Latest            map[string]LatestCommitInfo `json:"latest"`
+	Sources           map[string]ModuleSource     `json:"sources"`
+	Errors            []RepoError                 `json:"errors,omitempty"`
+}
+
+func (this *Parsed) GetSnapshot() Snapshot {
+	createdAt := this.scannedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	result := Snapshot{
+		Version:           SnapshotVersion,
+		CreatedAt:         createdAt,
+		Org:               this.org,
+		Host:              this.host,
+		IncludePrerelease: this.includePrerelease,
+		LatestGoVersion:   this.LatestGoVersion,
+		Repos:             this.Repos,
+		ModFiles:          map[string]string{},
+		Latest:            this.Latest,
+		Sources:           this.Sources,
+		Errors:            this.Errors,
+	}
+	for name, content := range this.ModFiles {
+		result.ModFiles[name] = string(content)
+	}
+	return result
+}
+
+// Parsed recreates the scan result by parsing the stored go.mod files
+func (this Snapshot) Parsed() (parsed *Parsed, err error) {
+	if this.Version != SnapshotVersion {
+		return parsed, fmt.Errorf("unsupported snapshot version %v (expected %v)", this.Version, SnapshotVersion)
+	}
+	parsed = &Parsed{
+		org:               this.Org,
+		host:              this.Host,
+		includePrerelease: this.IncludePrerelease,
+		scannedAt:         this.CreatedAt,
+		LatestGoVersion:   this.LatestGoVersion,
+		Repos:             this.Repos,
+		Modules:           map[string]*modfile.File{},
+		ModFiles:          map[string][]byte{},
+		Inverse:           map[string][]InverseIndexModRef{},
+		Latest:            this.Latest,
+		Sources:           this.Sources,
+		Errors:            this.Errors,
+	}
+	if parsed.Latest == nil {
+		parsed.Latest = map[string]LatestCommitInfo{}
+	}
+	if parsed.Sources == nil {
+		parsed.Sources = map[string]ModuleSource{}
+	}
+	for name, content := range this.ModFiles {
+		parsed.ModFiles[name] = []byte(content)
+		parsed.Modules[name], err = parseModFile(this.Sources[name].Path, []byte(content))
+		if err != nil {
+			return parsed, fmt.Errorf("invalid go.mod of %v in snapshot: %w", name, err)
+		}
+	}
+	parsed.buildInverseIndex()
+	return parsed, nil
+}
+
+func ReadSnapshotFile(file string) (snapshot Snapshot, err error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(content, &snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to parse snapshot %v: %w", file, err)
+	}
+	return snapshot, nil
+}
+
+// WriteSnapshotFile writes the snapshot as indented json, the file is never partially written
+func WriteSnapshotFile(file string, snapshot Snapshot) error {
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, content)
+}
+
+// LoadSnapshotFile reads the snapshot and recreates its scan result
+func LoadSnapshotFile(file string) (parsed *Parsed, err error) {
+	snapshot, err := ReadSnapshotFile(file)
+	if err != nil {
+		return parsed, err
+	}
+	return snapshot.Parsed()
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotReplay(t *testing.T) {
+	for _, encoding := range []string{EncodingText, EncodingJson, EncodingJsonData} {
+		t.Run(encoding, func(t *testing.T) {
+			snapshotFile := filepath.Join(t.TempDir(), "snapshot.json")
+			env := startEncodingTestEnv(t)
+			config := testConfig(env)
+			config.OutputEncode = encoding
+			config.SaveSnapshot = snapshotFile
+			expected := runMopher(t, config)
+
+			// the replay needs neither the org nor network access
+			env.Server.Close()
+			replay := MopherConfig{
+				LoadSnapshot:   snapshotFile,
+				OutputTemplate: config.OutputTemplate,
+				OutputEncode:   encoding,
+				WarnUnsyncDev:  config.WarnUnsyncDev,
+				WarnGoVersion:  config.WarnGoVersion,
+			}
+			if actual := runMopher(t, replay); actual != expected {
+				t.Errorf("report of replayed snapshot differs\nexpected:\n%v\nactual:\n%v", expected, actual)
+			}
+		})
+	}
+}
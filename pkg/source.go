@@ -0,0 +1,83 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"time"
+)
+
+const (
+	ForgeGithub  = "github"
+	ForgeGitlab  = "gitlab"
+	ForgeGitea   = "gitea"
+	ForgeForgejo = "forgejo" //uses the gitea source
+	ForgeLocal   = "local"
+)
+
+var ErrFileNotFound = errors.New("file not found")
+
+// Repository is the forge independent description of a scanned repository
+type Repository struct {
+	Name            string    `json:"name"`
+	FullName        string    `json:"full_name"`
+	DefaultBranch   string    `json:"default_branch"`
+	CloneUrl        string    `json:"clone_url"`
+	HtmlUrl         string    `json:"html_url"`
+	Forge           string    `json:"forge,omitempty"` //ForgeGithub, ForgeGitlab, ForgeGitea or ForgeLocal
+	Language        string    `json:"language,omitempty"`
+	LanguageUnknown bool      `json:"language_unknown,omitempty"` //set by sources that never report a language (gitlab, local) and for gitea repositories without language; these repositories are scanned regardless of Language
+	Archived        bool      `json:"archived"`
+	PushedAt        time.Time `json:"pushed_at"` //time of the last push to any branch or tag, zero if unknown. only GitHub reports an exact push time
+}
+
+// RepoSource lists the repositories of an org (or group) and provides access to their files and git refs
+type RepoSource interface {
+	// Host is the expected prefix of module names (e.g. github.com)
+	Host() string
+	ListRepos(ctx context.Context, org string) ([]Repository, error)
+	// ListModFiles returns the paths of all go.mod files in the default branch (see isModFilePath)
+	ListModFiles(ctx context.Context, repo Repository) ([]string, error)
+	// GetFile returns the content of filePath on the default branch or ErrFileNotFound
+	GetFile(ctx context.Context, repo Repository, filePath string) ([]byte, error)
+	// ListRefs returns HEAD, branch and tag refs of the repository
+	ListRefs(ctx context.Context, repo Repository) ([]*plumbing.Reference, error)
+}
+
+// repoReleaser is implemented by sources that hold resources per repository (e.g. in-memory clones);
+// release is called after all files and refs of the repository are read
+type repoReleaser interface {
+	release(repo Repository)
+}
+
+func NewRepoSource(config MopherConfig) (RepoSource, error) {
+	switch config.Forge {
+	case "", ForgeGithub:
+		return newGithubClient(config)
+	case ForgeGitlab:
+		return newGitlabClient(config)
+	case ForgeGitea, ForgeForgejo:
+		return newGiteaClient(config)
+	case ForgeLocal:
+		return newLocalSource(config.LocalDir, config.LocalHost)
+	default:
+		return nil, fmt.Errorf("unknown forge %v", config.Forge)
+	}
+}
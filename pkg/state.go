@@ -0,0 +1,73 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"slices"
+)
+
+// loadScanState reads the snapshot of the previous scan, used to skip repositories without new pushes.
+// returns nil if the file does not exist, is invalid or was created with different scan parameters
+func loadScanState(file string, parsed *Parsed) *Snapshot {
+	state, err := ReadSnapshotFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		slog.Warn("unable to read scan state, scanning all repositories", "file", file, "err", err)
+		return nil
+	}
+	if state.Version != SnapshotVersion || state.Org != parsed.org || state.Host != parsed.host || state.IncludePrerelease != parsed.includePrerelease {
+		slog.Info("scan state belongs to other scan parameters, scanning all repositories", "file", file)
+		return nil
+	}
+	return &state
+}
+
+// getRepoModules returns the stored modules of the repository, if it was not pushed since the snapshot was created.
+// repositories with unknown push time or errors in the previous scan are never reused
+func (this *Snapshot) getRepoModules(repo Repository) (modules map[string]repoModule, ok bool) {
+	if this == nil || repo.PushedAt.IsZero() {
+		return nil, false
+	}
+	index := slices.IndexFunc(this.Repos, func(r Repository) bool {
+		return r.FullName == repo.FullName
+	})
+	if index < 0 || !this.Repos[index].PushedAt.Equal(repo.PushedAt) || this.Repos[index].DefaultBranch != repo.DefaultBranch {
+		return nil, false
+	}
+	if slices.ContainsFunc(this.Errors, func(e RepoError) bool { return e.Repo == repo.FullName }) {
+		return nil, false
+	}
+	modules = map[string]repoModule{}
+	for name, source := range this.Sources {
+		if source.Repo != repo.FullName {
+			continue
+		}
+		content := []byte(this.ModFiles[name])
+		file, err := parseModFile(source.Path, content)
+		if err != nil {
+			slog.Debug("unable to parse stored go.mod", "module", name, "err", err)
+			return nil, false
+		}
+		modules[source.Path] = repoModule{content: content, file: file, latest: this.Latest[name]}
+	}
+	return modules, true
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	a := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0"))
+	env := startOrg(t, libRepo(), a)
+	config := testConfig(env)
+	config.StateFile = stateFile
+	expected := runMopher(t, config)
+	if env.Requests("/raw/") == 0 {
+		t.Fatal("first run fetched no go.mod file")
+	}
+
+	// repositories without new pushes are not fetched again
+	prefixes := []string{"/raw/", "/api/v3/repos/", "/git/"}
+	requests := map[string]int{}
+	for _, prefix := range prefixes {
+		requests[prefix] = env.Requests(prefix)
+	}
+	if actual := runMopher(t, config); actual != expected {
+		t.Errorf("report of reused state differs\nexpected:\n%v\nactual:\n%v", expected, actual)
+	}
+	for _, prefix := range prefixes {
+		if env.Requests(prefix) != requests[prefix] {
+			t.Errorf("unexpected %v requests for unchanged repositories", prefix)
+		}
+	}
+
+	// pushed repositories are scanned again
+	env = startOrg(t, libRepo(), withRelease(a, "v0.1.0"))
+	config = testConfig(env)
+	config.StateFile = stateFile
+	runMopher(t, config)
+	if env.Requests("/raw/org/a/") == 0 || env.Requests("/raw/org/lib/") != 0 {
+		t.Errorf("expected only org/a to be fetched, got %v requests for org/a and %v for org/lib", env.Requests("/raw/org/a/"), env.Requests("/raw/org/lib/"))
+	}
+
+	// listing errors keep the state
+	state := readTestFile(t, stateFile)
+	config.Org = "unknown"
+	if err := Mopher(context.Background(), config); err == nil {
+		t.Error("expected listing error")
+	}
+	if readTestFile(t, stateFile) != state {
+		t.Error("state changed after listing error")
+	}
+}
+
+func TestStateFileRescansFailedRepos(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	a := goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.2.0"))
+	config := testConfig(startOrg(t, unavailable(libRepo()), a))
+	config.StateFile = stateFile
+	runMopher(t, config)
+
+	// the failed repository is scanned again although it was not pushed
+	env := startOrg(t, libRepo(), a)
+	config = testConfig(env)
+	config.StateFile = stateFile
+	output := runMopher(t, config)
+	if env.Requests("/raw/org/lib/") == 0 || env.Requests("/raw/org/a/") != 0 {
+		t.Errorf("expected only org/lib to be fetched, got %v requests for org/lib and %v for org/a", env.Requests("/raw/org/lib/"), env.Requests("/raw/org/a/"))
+	}
+	expected := runMopher(t, testConfig(env))
+	if output != expected {
+		t.Errorf("report differs from a full scan\nexpected:\n%v\nactual:\n%v", expected, output)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testenv
+
+import (
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// CommitTime is the time of the first commit of every repository; following commits are one hour apart.
+// fixed times make commit hashes and therefore the reports deterministic
+var CommitTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// buildRepository creates an in memory git repository with the commits and branches of the description
+func buildRepository(desc Repo) (*git.Repository, error) {
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return nil, err
+	}
+	return repo, addHistory(repo, desc)
+}
+
+// BuildCheckout creates a checkout with the commits and branches of the description in dir, e.g. to test the local source
+func BuildCheckout(tb testing.TB, dir string, desc Repo) *git.Repository {
+	tb.Helper()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		tb.Fatalf("unable to init %v: %v", dir, err)
+	}
+	err = addHistory(repo, desc)
+	if err != nil {
+		tb.Fatalf("unable to build checkout %v: %v", dir, err)
+	}
+	return repo
+}
+
+// addHistory adds the commits and branches of the description to an empty repository
+func addHistory(repo *git.Repository, desc Repo) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	branch := plumbing.NewBranchReferenceName(desc.GetDefaultBranch())
+	err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+	if err != nil {
+		return err
+	}
+	when := CommitTime
+	var head plumbing.Hash
+	for _, commit := range desc.Commits {
+		head, err = addCommit(repo, worktree, commit, when)
+		if err != nil {
+			return err
+		}
+		when = when.Add(time.Hour)
+	}
+	if !desc.DevBranch {
+		return nil
+	}
+	dev := plumbing.NewBranchReferenceName("dev")
+	err = repo.Storer.SetReference(plumbing.NewHashReference(dev, head))
+	if err != nil {
+		return err
+	}
+	if len(desc.DevCommits) == 0 {
+		return nil
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: dev})
+	if err != nil {
+		return err
+	}
+	for _, commit := range desc.DevCommits {
+		_, err = addCommit(repo, worktree, commit, when)
+		if err != nil {
+			return err
+		}
+		when = when.Add(time.Hour)
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Branch: branch})
+}
+
+func addCommit(repo *git.Repository, worktree *git.Worktree, commit Commit, when time.Time) (hash plumbing.Hash, err error) {
+	names := []string{}
+	for name := range commit.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := worktree.Filesystem.Create(name)
+		if err != nil {
+			return hash, err
+		}
+		_, err = file.Write([]byte(commit.Files[name]))
+		if err != nil {
+			file.Close()
+			return hash, err
+		}
+		err = file.Close()
+		if err != nil {
+			return hash, err
+		}
+		_, err = worktree.Add(name)
+		if err != nil {
+			return hash, err
+		}
+	}
+	signature := &object.Signature{Name: "testenv", Email: "testenv@example.com", When: when}
+	message := commit.Message
+	if message == "" {
+		message = "commit"
+	}
+	hash, err = worktree.Commit(message, &git.CommitOptions{Author: signature, Committer: signature, AllowEmptyCommits: true})
+	if err != nil {
+		return hash, err
+	}
+	for _, tag := range commit.Tags {
+		_, err = repo.CreateTag(tag, hash, nil)
+		if err != nil {
+			return hash, err
+		}
+	}
+	return hash, nil
+}
+
+// repoLoader serves the repositories by endpoint path (/<owner>/<name>)
+type repoLoader map[string]storer.Storer
+
+func (this repoLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	s, ok := this[ep.Path]
+	if !ok {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return s, nil
+}
+
+// serveInfoRefs answers the first request of the git smart http protocol (used by ls-remote, fetch and clone)
+func (this *Env) serveInfoRefs(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.PathValue("path"), "/info/refs") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("service") != transport.UploadPackServiceName {
+		http.Error(w, "only git-upload-pack is supported", http.StatusForbidden)
+		return
+	}
+	session, err := this.newUploadPackSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	refs, err := session.AdvertisedReferencesContext(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refs.Prefix = [][]byte{[]byte("# service=" + transport.UploadPackServiceName), pktline.Flush}
+	w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+	_ = refs.Encode(w)
+}
+
+// serveUploadPack sends the objects requested by fetch or clone
+func (this *Env) serveUploadPack(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.PathValue("path"), "/git-upload-pack") {
+		http.NotFound(w, r)
+		return
+	}
+	session, err := this.newUploadPackSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	req := packp.NewUploadPackRequest()
+	err = req.Decode(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := session.UploadPack(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Close()
+	w.Header().Set("Content-Type", "application/x-git-upload-pack-result")
+	_ = resp.Encode(w)
+}
+
+func (this *Env) newUploadPackSession(r *http.Request) (transport.UploadPackSession, error) {
+	//the repository path may contain subgroups, e.g. /git/group/sub/repo.git/info/refs
+	repoPath, _, _ := strings.Cut(r.PathValue("path"), ".git/")
+	endpoint, err := transport.NewEndpoint("/" + repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewServer(this.loader).NewUploadPackSession(endpoint, nil)
+}
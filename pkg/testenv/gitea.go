@@ -0,0 +1,91 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testenv
+
+import (
+	"net/http"
+)
+
+type giteaRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	CloneUrl      string `json:"clone_url"`
+	HtmlUrl       string `json:"html_url"`
+	Language      string `json:"language"`
+	Archived      bool   `json:"archived"`
+	Empty         bool   `json:"empty"`
+}
+
+// serveGiteaRepos pages like gitea: limit is reduced to MaxPageSize and pages after the last one are empty
+func (this *Env) serveGiteaRepos(w http.ResponseWriter, r *http.Request) {
+	for _, org := range this.description.Orgs {
+		if org.Name != r.PathValue("org") {
+			continue
+		}
+		result := []giteaRepository{}
+		for _, desc := range org.Repos {
+			fullName := org.Name + "/" + desc.Name
+			result = append(result, giteaRepository{
+				Name:          desc.Name,
+				FullName:      fullName,
+				DefaultBranch: desc.GetDefaultBranch(),
+				CloneUrl:      this.CloneUrl(fullName),
+				HtmlUrl:       this.Server.URL + "/" + fullName,
+				Language:      desc.GetLanguage(),
+				Archived:      desc.Archived,
+				Empty:         len(desc.Commits) == 0,
+			})
+		}
+		_, start, end := getPage(r, "limit", len(result))
+		writeJson(w, r, result[start:end])
+		return
+	}
+	http.Error(w, `{"message":"GetOrgByName"}`, http.StatusNotFound)
+}
+
+type giteaTreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+func (this *Env) serveGiteaTree(w http.ResponseWriter, r *http.Request) {
+	commit, err := this.getCommit(r.PathValue("owner")+"/"+r.PathValue("repo"), r.PathValue("ref"))
+	if err != nil {
+		http.Error(w, `{"message":"sha not found"}`, http.StatusNotFound)
+		return
+	}
+	entries, err := getTreeEntries(commit, r.URL.Query().Get("recursive") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, start, end := getPage(r, "per_page", len(entries))
+	page := []giteaTreeEntry{}
+	for _, entry := range entries[start:end] {
+		page = append(page, giteaTreeEntry{Path: entry.path, Type: entry.entryType})
+	}
+	writeJson(w, r, map[string]interface{}{"tree": page, "truncated": end < len(entries), "total_count": len(entries)})
+}
+
+func (this *Env) serveGiteaFile(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+	this.serveFile(w, r, r.PathValue("owner")+"/"+r.PathValue("repo"), ref, r.PathValue("path"))
+}
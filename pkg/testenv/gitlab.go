@@ -0,0 +1,109 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testenv
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type gitlabProject struct {
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	HttpUrlToRepo     string `json:"http_url_to_repo"`
+	WebUrl            string `json:"web_url"`
+	Archived          bool   `json:"archived"`
+	EmptyRepo         bool   `json:"empty_repo"`
+}
+
+// serveGitlabProjects lists the projects of orgs named like the group, with include_subgroups also of orgs named <group>/...
+func (this *Env) serveGitlabProjects(w http.ResponseWriter, r *http.Request) {
+	group := r.PathValue("group")
+	subgroups := r.URL.Query().Get("include_subgroups") == "true"
+	found := false
+	result := []gitlabProject{}
+	for _, org := range this.description.Orgs {
+		if org.Name != group && !(subgroups && strings.HasPrefix(org.Name, group+"/")) {
+			continue
+		}
+		found = found || org.Name == group
+		for _, desc := range org.Repos {
+			fullName := org.Name + "/" + desc.Name
+			result = append(result, gitlabProject{
+				Path:              desc.Name,
+				PathWithNamespace: fullName,
+				DefaultBranch:     desc.GetDefaultBranch(),
+				HttpUrlToRepo:     this.CloneUrl(fullName),
+				WebUrl:            this.Server.URL + "/" + fullName,
+				Archived:          desc.Archived,
+				EmptyRepo:         len(desc.Commits) == 0,
+			})
+		}
+	}
+	if !found {
+		http.Error(w, `{"message":"404 Group Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeGitlabPage(w, r, result)
+}
+
+type gitlabTreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+func (this *Env) serveGitlabTree(w http.ResponseWriter, r *http.Request) {
+	commit, err := this.getCommit(r.PathValue("project"), getGitlabRef(r))
+	if err != nil {
+		http.Error(w, `{"message":"404 Tree Not Found"}`, http.StatusNotFound)
+		return
+	}
+	entries, err := getTreeEntries(commit, r.URL.Query().Get("recursive") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := []gitlabTreeEntry{}
+	for _, entry := range entries {
+		result = append(result, gitlabTreeEntry{Path: entry.path, Type: entry.entryType})
+	}
+	writeGitlabPage(w, r, result)
+}
+
+func (this *Env) serveGitlabFile(w http.ResponseWriter, r *http.Request) {
+	this.serveFile(w, r, r.PathValue("project"), getGitlabRef(r), r.PathValue("path"))
+}
+
+func getGitlabRef(r *http.Request) string {
+	if ref := r.URL.Query().Get("ref"); ref != "" {
+		return ref
+	}
+	return "HEAD"
+}
+
+// writeGitlabPage writes the requested page of items and links the following page with the X-Next-Page header
+func writeGitlabPage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, start, end := getPage(r, "per_page", len(items))
+	if end < len(items) {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	} else {
+		w.Header().Set("X-Next-Page", "")
+	}
+	writeJson(w, r, items[start:end])
+}
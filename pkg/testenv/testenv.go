@@ -0,0 +1,420 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package testenv serves declaratively described orgs through fake GitHub, GitLab and Gitea apis, a raw content server,
+// a docker hub golang tags endpoint and an in-process git smart http server, so that scans run without internet access.
+package testenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Description of everything served by an Env
+type Description struct {
+	Orgs    []Org
+	GoTags  []string      //tags of the golang docker image, e.g. 1.22.1 or 1.23rc1
+	Latency time.Duration //delays every response until it passes or the request is canceled, e.g. to test timeouts
+}
+
+type Org struct {
+	Name  string
+	Repos []Repo
+}
+
+type Repo struct {
+	Name          string
+	DefaultBranch string //defaults to main
+	Language      string //language reported by the api, defaults to Go
+	NoLanguage    bool   //the api reports an empty language, like gitea without language statistics
+	Archived      bool
+	Commits       []Commit //commits of the default branch, oldest first
+	DevBranch     bool     //creates a dev branch at the last commit of the default branch
+	DevCommits    []Commit //additional commits of the dev branch
+	Unavailable   bool     //git requests (ls-remote, fetch, clone) of the repository fail with not found
+}
+
+// Commit adds or changes Files and creates lightweight Tags pointing to the commit
+type Commit struct {
+	Message string
+	Files   map[string]string //file content by path
+	Tags    []string
+}
+
+func (this Repo) GetDefaultBranch() string {
+	if this.DefaultBranch == "" {
+		return "main"
+	}
+	return this.DefaultBranch
+}
+
+func (this Repo) GetLanguage() string {
+	if this.NoLanguage {
+		return ""
+	}
+	if this.Language == "" {
+		return "Go"
+	}
+	return this.Language
+}
+
+// Env is a running fake forge. all urls point to the same httptest.Server
+type Env struct {
+	Server       *httptest.Server
+	ApiUrl       string //GitHub Enterprise style api url (<server>/api/v3/)
+	RawUrl       string
+	GitlabUrl    string //GitLab base url (<server>/gitlab), the api is served at <server>/gitlab/api/v4/
+	GiteaUrl     string //Gitea base url (<server>/gitea), the api is served at <server>/gitea/api/v1/
+	DockerhubUrl string
+	description  Description
+	repos        map[string]*git.Repository //by full name (<org>/<repo>)
+	loader       repoLoader
+	handler      http.Handler
+	mux          sync.Mutex
+	requests     []Request
+}
+
+// Request is a served request
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Status int
+}
+
+// statusRecorder captures the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	this.status = status
+	this.ResponseWriter.WriteHeader(status)
+}
+
+// Start serves the description until the test ends
+func Start(tb testing.TB, description Description) *Env {
+	tb.Helper()
+	env := New(tb)
+	env.Serve(tb, description)
+	return env
+}
+
+// New reserves the address of an Env without serving anything yet, so that Host can be used in the description (e.g. in module names).
+// Serve starts the Env
+func New(tb testing.TB) *Env {
+	env := &Env{
+		repos:  map[string]*git.Repository{},
+		loader: repoLoader{},
+	}
+	env.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		select {
+		case <-time.After(env.description.Latency):
+			env.handler.ServeHTTP(recorder, r)
+		case <-r.Context().Done():
+			recorder.status = 0 //canceled by the client
+		}
+		env.mux.Lock()
+		env.requests = append(env.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Status: recorder.status})
+		env.mux.Unlock()
+	}))
+	tb.Cleanup(env.Server.Close)
+	serverUrl := "http://" + env.Host()
+	env.ApiUrl = serverUrl + "/api/v3/"
+	env.RawUrl = serverUrl + "/raw/"
+	env.GitlabUrl = serverUrl + "/gitlab"
+	env.GiteaUrl = serverUrl + "/gitea"
+	env.DockerhubUrl = serverUrl + "/dockerhub/v2/repositories/library/golang/tags"
+	return env
+}
+
+// Host returns the host and port of the server
+func (this *Env) Host() string {
+	return this.Server.Listener.Addr().String()
+}
+
+// Serve starts serving the description of an Env created by New
+func (this *Env) Serve(tb testing.TB, description Description) {
+	tb.Helper()
+	this.description = description
+	for _, org := range description.Orgs {
+		for _, desc := range org.Repos {
+			repo, err := buildRepository(desc)
+			if err != nil {
+				tb.Fatalf("unable to build repository %v/%v: %v", org.Name, desc.Name, err)
+			}
+			fullName := org.Name + "/" + desc.Name
+			this.repos[fullName] = repo
+			if !desc.Unavailable {
+				this.loader["/"+fullName] = repo.Storer
+			}
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/orgs/{org}/repos", this.serveOrgRepos)
+	mux.HandleFunc("GET /api/v3/repos/{owner}/{repo}/git/trees/{ref}", this.serveTree)
+	mux.HandleFunc("GET /raw/{owner}/{repo}/{ref}/{path...}", this.serveRaw)
+	mux.HandleFunc("GET /gitlab/api/v4/groups/{group}/projects", this.serveGitlabProjects)
+	mux.HandleFunc("GET /gitlab/api/v4/projects/{project}/repository/tree", this.serveGitlabTree)
+	mux.HandleFunc("GET /gitlab/api/v4/projects/{project}/repository/files/{path}/raw", this.serveGitlabFile)
+	mux.HandleFunc("GET /gitea/api/v1/orgs/{org}/repos", this.serveGiteaRepos)
+	mux.HandleFunc("GET /gitea/api/v1/repos/{owner}/{repo}/git/trees/{ref}", this.serveGiteaTree)
+	mux.HandleFunc("GET /gitea/api/v1/repos/{owner}/{repo}/raw/{path...}", this.serveGiteaFile)
+	mux.HandleFunc("GET /git/{path...}", this.serveInfoRefs)
+	mux.HandleFunc("POST /git/{path...}", this.serveUploadPack)
+	mux.HandleFunc("GET /dockerhub/v2/repositories/library/golang/tags", this.serveGolangTags)
+	this.handler = mux
+	this.Server.Start()
+}
+
+// CloneUrl returns the git smart http url of the repository
+func (this *Env) CloneUrl(fullName string) string {
+	return this.Server.URL + "/git/" + fullName + ".git"
+}
+
+// Hash returns the full commit hash of a branch or tag, e.g. to build pseudo-versions
+func (this *Env) Hash(tb testing.TB, fullName string, rev string) string {
+	tb.Helper()
+	repo, ok := this.repos[fullName]
+	if !ok {
+		tb.Fatalf("unknown repository %v", fullName)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		tb.Fatalf("unable to resolve %v in %v: %v", rev, fullName, err)
+	}
+	return hash.String()
+}
+
+// Requests counts the served requests whose path starts with prefix, e.g. "/raw/org/repo/"
+func (this *Env) Requests(prefix string) int {
+	return len(this.GetRequests(prefix))
+}
+
+// GetRequests returns the served requests whose path starts with prefix in the order they were answered
+func (this *Env) GetRequests(prefix string) (result []Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, request := range this.requests {
+		if strings.HasPrefix(request.Path, prefix) {
+			result = append(result, request)
+		}
+	}
+	return result
+}
+
+type githubRepository struct {
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	DefaultBranch string    `json:"default_branch"`
+	CloneUrl      string    `json:"clone_url"`
+	HtmlUrl       string    `json:"html_url"`
+	Language      string    `json:"language"`
+	Archived      bool      `json:"archived"`
+	PushedAt      time.Time `json:"pushed_at"`
+}
+
+func (this *Env) serveOrgRepos(w http.ResponseWriter, r *http.Request) {
+	result := []githubRepository{}
+	if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+		writeJson(w, r, result)
+		return
+	}
+	for _, org := range this.description.Orgs {
+		if org.Name != r.PathValue("org") {
+			continue
+		}
+		for _, desc := range org.Repos {
+			fullName := org.Name + "/" + desc.Name
+			pushedAt := CommitTime.Add(time.Duration(len(desc.Commits)+len(desc.DevCommits)) * time.Hour)
+			result = append(result, githubRepository{
+				Name:          desc.Name,
+				FullName:      fullName,
+				DefaultBranch: desc.GetDefaultBranch(),
+				CloneUrl:      this.CloneUrl(fullName),
+				HtmlUrl:       this.Server.URL + "/" + fullName,
+				Language:      desc.GetLanguage(),
+				Archived:      desc.Archived,
+				PushedAt:      pushedAt,
+			})
+		}
+		writeJson(w, r, result)
+		return
+	}
+	http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+}
+
+type githubTreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	Sha  string `json:"sha"`
+}
+
+func (this *Env) serveTree(w http.ResponseWriter, r *http.Request) {
+	commit, err := this.getCommit(r.PathValue("owner")+"/"+r.PathValue("repo"), r.PathValue("ref"))
+	if err != nil {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entries := []githubTreeEntry{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		entries = append(entries, githubTreeEntry{Path: file.Name, Mode: file.Mode.String(), Type: "blob", Sha: file.Hash.String()})
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, r, map[string]interface{}{"sha": tree.Hash.String(), "tree": entries, "truncated": false})
+}
+
+func (this *Env) serveRaw(w http.ResponseWriter, r *http.Request) {
+	this.serveFile(w, r, r.PathValue("owner")+"/"+r.PathValue("repo"), r.PathValue("ref"), r.PathValue("path"))
+}
+
+// serveFile sends the content of the file at ref, the raw endpoints of all apis answer like this
+func (this *Env) serveFile(w http.ResponseWriter, r *http.Request, fullName string, ref string, filePath string) {
+	commit, err := this.getCommit(fullName, ref)
+	if err != nil {
+		http.Error(w, "404: Not Found", http.StatusNotFound)
+		return
+	}
+	file, err := commit.File(filePath)
+	if err != nil {
+		http.Error(w, "404: Not Found", http.StatusNotFound)
+		return
+	}
+	content, err := file.Contents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeContent(w, r, "text/plain; charset=utf-8", []byte(content))
+}
+
+type treeEntry struct {
+	path      string
+	entryType string //blob or tree
+}
+
+// getTreeEntries lists the files and, if recursive, also the content of directories of the commit
+func getTreeEntries(commit *object.Commit, recursive bool) (result []treeEntry, err error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return result, err
+	}
+	walker := object.NewTreeWalker(tree, recursive, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		entryType := "blob"
+		if !entry.Mode.IsFile() {
+			entryType = "tree"
+		}
+		result = append(result, treeEntry{path: name, entryType: entryType})
+	}
+}
+
+func (this *Env) serveGolangTags(w http.ResponseWriter, r *http.Request) {
+	results := []map[string]string{}
+	for _, tag := range this.description.GoTags {
+		results = append(results, map[string]string{"name": tag})
+	}
+	writeJson(w, r, map[string]interface{}{"count": len(results), "results": results})
+}
+
+func (this *Env) getCommit(fullName string, ref string) (*object.Commit, error) {
+	repo, ok := this.repos[trimGitSuffix(fullName)]
+	if !ok {
+		return nil, errors.New("unknown repository")
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(*hash)
+}
+
+// MaxPageSize limits the page size of the gitlab and gitea apis, so that tests page through the results
+const MaxPageSize = 2
+
+// getPage returns the requested page and its bounds, the page size is read from the sizeParam and limited to MaxPageSize
+func getPage(r *http.Request, sizeParam string, count int) (page int, start int, end int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err := strconv.Atoi(r.URL.Query().Get(sizeParam))
+	if err != nil || size < 1 || size > MaxPageSize {
+		size = MaxPageSize
+	}
+	start = min((page-1)*size, count)
+	end = min(start+size, count)
+	return page, start, end
+}
+
+func writeJson(w http.ResponseWriter, r *http.Request, value interface{}) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeContent(w, r, "application/json", content)
+}
+
+// writeContent sends an ETag with the content and answers matching If-None-Match requests with 304 Not Modified, like GitHub and GitLab do
+func writeContent(w http.ResponseWriter, r *http.Request, contentType string, content []byte) {
+	hash := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(hash[:]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(content)
+}
+
+func trimGitSuffix(name string) string {
+	return strings.TrimSuffix(name, ".git")
+}
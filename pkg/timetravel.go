@@ -0,0 +1,259 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitAuthSource is implemented by sources that need credentials for git requests
+type gitAuthSource interface {
+	gitAuth() transport.AuthMethod
+}
+
+// timeTravelSource wraps a RepoSource and describes the repositories as they were at a past time.
+// every repository is cloned into memory; files are read from the last default branch commit before 'at'
+// and only branches and tags that existed at 'at' are listed
+type timeTravelSource struct {
+	source RepoSource
+	at     time.Time
+	mux    sync.Mutex
+	repos  map[string]*timeTravelRepo
+}
+
+type timeTravelRepo struct {
+	once   sync.Once
+	err    error
+	commit *object.Commit //nil if the default branch has no commit before 'at'
+	refs   []*plumbing.Reference
+}
+
+func newTimeTravelSource(source RepoSource, at time.Time) *timeTravelSource {
+	return &timeTravelSource{source: source, at: at, repos: map[string]*timeTravelRepo{}}
+}
+
+func (this *timeTravelSource) Host() string {
+	return this.source.Host()
+}
+
+// ListRepos returns the current repositories; the push time is not known for the past
+func (this *timeTravelSource) ListRepos(ctx context.Context, org string) (result []Repository, err error) {
+	result, err = this.source.ListRepos(ctx, org)
+	for i := range result {
+		result[i].PushedAt = time.Time{}
+	}
+	return result, err
+}
+
+func (this *timeTravelSource) ListModFiles(ctx context.Context, repo Repository) (result []string, err error) {
+	state, err := this.getRepo(ctx, repo)
+	if err != nil || state.commit == nil {
+		return result, err
+	}
+	tree, err := state.commit.Tree()
+	if err != nil {
+		return result, err
+	}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if isModFilePath(file.Name) {
+			result = append(result, file.Name)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (this *timeTravelSource) GetFile(ctx context.Context, repo Repository, filePath string) ([]byte, error) {
+	state, err := this.getRepo(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	if state.commit == nil {
+		return nil, ErrFileNotFound
+	}
+	file, err := state.commit.File(filePath)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	return []byte(content), err
+}
+
+func (this *timeTravelSource) ListRefs(ctx context.Context, repo Repository) ([]*plumbing.Reference, error) {
+	state, err := this.getRepo(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return state.refs, nil
+}
+
+// release frees the in-memory clone, see repoReleaser
+func (this *timeTravelSource) release(repo Repository) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	delete(this.repos, repo.FullName)
+}
+
+func (this *timeTravelSource) getRepo(ctx context.Context, repo Repository) (*timeTravelRepo, error) {
+	this.mux.Lock()
+	state, ok := this.repos[repo.FullName]
+	if !ok {
+		state = &timeTravelRepo{}
+		this.repos[repo.FullName] = state
+	}
+	this.mux.Unlock()
+	state.once.Do(func() {
+		state.err = state.load(ctx, this.source, repo, this.at)
+	})
+	return state, state.err
+}
+
+func (this *timeTravelRepo) load(ctx context.Context, source RepoSource, repo Repository, at time.Time) error {
+	var auth transport.AuthMethod
+	if authSource, ok := source.(gitAuthSource); ok {
+		auth = authSource.gitAuth()
+	}
+	slog.Debug("git clone "+repo.CloneUrl, "at", at)
+	gitRepo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:        repo.CloneUrl,
+		Auth:       auth,
+		NoCheckout: true,
+		Tags:       git.AllTags,
+	})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: unable to clone %v: %w", LatestInfoError, repo.CloneUrl, err)
+	}
+
+	defaultBranch := repo.DefaultBranch
+	if defaultBranch == "" {
+		head, err := gitRepo.Head()
+		if err != nil {
+			return err
+		}
+		defaultBranch = head.Name().Short()
+	}
+	this.commit, err = getLastCommitBefore(gitRepo, plumbing.NewRemoteReferenceName("origin", defaultBranch), at)
+	if err != nil {
+		return err
+	}
+	if this.commit == nil {
+		slog.Debug("no commit before time travel date", "repo-name", repo.FullName)
+		return nil
+	}
+	branch := plumbing.NewBranchReferenceName(defaultBranch)
+	this.refs = []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, branch),
+		plumbing.NewHashReference(branch, this.commit.Hash),
+	}
+	devCommit, err := getLastCommitBefore(gitRepo, plumbing.NewRemoteReferenceName("origin", "dev"), at)
+	if err != nil {
+		return err
+	}
+	if devCommit != nil {
+		this.refs = append(this.refs, plumbing.NewHashReference("refs/heads/dev", devCommit.Hash))
+	}
+	tags, err := gitRepo.Tags()
+	if err != nil {
+		return err
+	}
+	return tags.ForEach(func(ref *plumbing.Reference) error {
+		created, err := getTagTime(gitRepo, ref)
+		if err != nil {
+			slog.Debug("ignored unreadable tag", "repo-name", repo.FullName, "tag", ref.Name().Short(), "err", err)
+			return nil
+		}
+		if !created.After(at) {
+			this.refs = append(this.refs, ref)
+		}
+		return nil
+	})
+}
+
+// getLastCommitBefore returns the newest commit (by committer time) reachable from the branch, that is not after 'at'.
+// returns nil if the branch does not exist or has no such commit
+func getLastCommitBefore(gitRepo *git.Repository, branch plumbing.ReferenceName, at time.Time) (result *object.Commit, err error) {
+	ref, err := gitRepo.Reference(branch, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commits, err := gitRepo.Log(&git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer commits.Close()
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if !commit.Committer.When.After(at) {
+			result = commit
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err == io.EOF {
+		err = nil
+	}
+	return result, err
+}
+
+// getTagTime returns the tagger time of annotated tags and the committer time of lightweight tags
+func getTagTime(gitRepo *git.Repository, ref *plumbing.Reference) (time.Time, error) {
+	tag, err := gitRepo.TagObject(ref.Hash())
+	if err == nil {
+		return tag.Tagger.When, nil
+	}
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return time.Time{}, err
+	}
+	commit, err := gitRepo.CommitObject(ref.Hash())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.Committer.When, nil
+}
+
+// ParseTimeTravelDate parses a date (2006-01-02, interpreted as end of this day in UTC) or a RFC 3339 time
+func ParseTimeTravelDate(value string) (time.Time, error) {
+	if strings.Contains(value, "T") {
+		return time.Parse(time.RFC3339, value)
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return date, err
+	}
+	return date.Add(24*time.Hour - time.Second), nil
+}
@@ -17,6 +17,7 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"golang.org/x/mod/modfile"
 	"strings"
@@ -27,20 +28,22 @@ type UpdateModeCommand struct {
 	Args []string
 }
 
-func RunUpdateMode(mod *modfile.File, internal bool) (commands []UpdateModeCommand, err error) {
+func RunUpdateMode(ctx context.Context, mod *modfile.File, internal bool) (commands []UpdateModeCommand, err error) {
 	org := getOrgOfGithubPath(mod.Module.Mod.Path)
 	for _, req := range mod.Require {
 		if getOrgOfGithubPath(req.Mod.Path) == org {
-			version, semantic := normalizeGoDependencyVersion(req.Mod.Version)
-			latest, err := getLatestInfoFromUrl("https://" + req.Mod.Path + ".git")
+			_, semantic := normalizeGoDependencyVersion(req.Mod.Version)
+			repoPath, modFilePath := splitGithubModulePath(req.Mod.Path)
+			refs, err := listRemoteRefs(ctx, "https://"+repoPath+".git", nil, 0)
 			if err != nil {
 				return nil, err
 			}
-			latestVersionStr := latest.MainHash
-			if semantic {
-				latestVersionStr = latest.LatestTag
+			latest, err := getLatestInfoFromRefs(refs, newTagSelection(req.Mod.Path, modFilePath, false))
+			if err != nil {
+				return nil, err
 			}
-			if version != latestVersionStr {
+			latestVersionStr := getExpectedDependencyVersion(semantic, latest)
+			if isDependencyVersionOutdated(req.Mod.Version, latest) {
 				commands = append(commands, UpdateModeCommand{
 					Cmd:  "go",
 					Args: []string{"get", fmt.Sprintf("%v@%v", req.Mod.Path, latestVersionStr)},
@@ -70,9 +73,19 @@ func RunUpdateMode(mod *modfile.File, internal bool) (commands []UpdateModeComma
 	return commands, nil
 }
 
+// splitGithubModulePath splits the module path into the repository path and the assumed go.mod location in the repository
+// (e.g. github.com/org/repo/api/v2 -> github.com/org/repo, api/v2/go.mod)
+func splitGithubModulePath(path string) (repoPath string, modFilePath string) {
+	parts := strings.Split(path, "/")
+	if len(parts) <= 3 {
+		return path, "go.mod"
+	}
+	return strings.Join(parts[:3], "/"), strings.Join(parts[3:], "/") + "/go.mod"
+}
+
 func getOrgOfGithubPath(path string) string {
 	parts := strings.Split(path, "/")
-	if len(parts) == 3 {
+	if len(parts) >= 3 {
 		return strings.Join(parts[:2], "/")
 	}
 	return path
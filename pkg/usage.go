@@ -0,0 +1,164 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const (
+	EncodingCsv = "text/csv"
+	EncodingTsv = "text/tab-separated-values"
+)
+
+const (
+	VersionTypeSemantic = "semantic"
+	VersionTypePseudo   = "pseudo"
+)
+
+// DependencyUsage is an entry of the Parsed.Inverse index
+type DependencyUsage struct {
+	Module         string `json:"module"`
+	Dependency     string `json:"dependency"`
+	UsedVersion    string `json:"used_version"` //as written in go.mod
+	VersionType    string `json:"version_type"` //semantic or pseudo
+	Indirect       bool   `json:"indirect"`
+	LatestTag      string `json:"latest_tag,omitempty"`       //only known for org dependencies
+	LatestMainHash string `json:"latest_main_hash,omitempty"` //only known for org dependencies
+	Lag            Lag    `json:"lag,omitempty"`              //only known for org dependencies
+}
+
+var dependencyUsageHeader = []string{"module", "dependency", "used_version", "version_type", "indirect", "latest_tag", "latest_main_hash", "lag"}
+
+// GetDependencyUsages lists the usages of org dependencies, sorted by dependency and module.
+// allDependencies adds the usages of non-org dependencies; dep optionally limits the result to a single dependency.
+func (this *Parsed) GetDependencyUsages(allDependencies bool, dep string) (result []DependencyUsage) {
+	result = []DependencyUsage{}
+	for dependency, refs := range this.Inverse {
+		latest, isOrgDependency := this.Latest[dependency]
+		if (!allDependencies && !isOrgDependency) || (dep != "" && dependency != dep) {
+			continue
+		}
+		for _, ref := range refs {
+			usage := DependencyUsage{
+				Module:      ref.UserModule,
+				Dependency:  dependency,
+				UsedVersion: ref.RawVersion,
+				VersionType: VersionTypePseudo,
+				Indirect:    ref.Indirect,
+			}
+			if ref.SemanticVersion {
+				usage.VersionType = VersionTypeSemantic
+			}
+			if isOrgDependency {
+				usage.LatestTag = latest.LatestTag
+				usage.LatestMainHash = latest.MainHash
+				usage.Lag = getDependencyLag(ref.RawVersion, latest)
+			}
+			result = append(result, usage)
+		}
+	}
+	slices.SortFunc(result, func(a, b DependencyUsage) int {
+		if c := strings.Compare(a.Dependency, b.Dependency); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Module, b.Module)
+	})
+	return result
+}
+
+func EncodeDependencyUsages(usages []DependencyUsage, encoding string) (string, error) {
+	switch encoding {
+	case EncodingJsonData:
+		temp, err := json.Marshal(usages)
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingJson:
+		text, err := encodeDependencyUsageTable(usages, '\t')
+		if err != nil {
+			return "", err
+		}
+		temp, err := json.Marshal(strings.TrimSpace(text))
+		if err != nil {
+			return "", err
+		}
+		return string(temp), nil
+	case EncodingCsv:
+		return encodeDependencyUsageTable(usages, ',')
+	case EncodingTsv, EncodingText:
+		return encodeDependencyUsageTable(usages, '\t')
+	default:
+		return "", fmt.Errorf("unexpected output encoding for dependency usages: %v", encoding)
+	}
+}
+
+func encodeDependencyUsageTable(usages []DependencyUsage, separator rune) (string, error) {
+	buf := strings.Builder{}
+	writer := csv.NewWriter(&buf)
+	writer.Comma = separator
+	err := writer.Write(dependencyUsageHeader)
+	if err != nil {
+		return "", err
+	}
+	for _, usage := range usages {
+		indirect := "false"
+		if usage.Indirect {
+			indirect = "true"
+		}
+		err = writer.Write([]string{usage.Module, usage.Dependency, usage.UsedVersion, usage.VersionType, indirect, usage.LatestTag, usage.LatestMainHash, string(usage.Lag)})
+		if err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+	return buf.String(), writer.Error()
+}
+
+// MopherUsage loads the org (or config.LoadSnapshot) and writes the dependency usages instead of the report
+func MopherUsage(ctx context.Context, config MopherConfig) error {
+	if config.Org == "" && config.LoadSnapshot == "" {
+		return errors.New("missing org input")
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	tmpl, err := template.New("templ").Parse(config.OutputTemplate)
+	if err != nil {
+		return err
+	}
+	parsed, err := loadParsed(ctx, config)
+	if err != nil {
+		return err
+	}
+	usages := parsed.GetDependencyUsages(config.UsageAllDependencies, config.Dep)
+	output, err := EncodeDependencyUsages(usages, config.OutputEncode)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, config, tmpl, output, map[string]interface{}{"Usages": usages})
+}
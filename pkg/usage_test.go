@@ -0,0 +1,67 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"github.com/SENERGY-Platform/mopher/pkg/testenv"
+	"testing"
+)
+
+func TestMopherUsage(t *testing.T) {
+	env := testenv.Start(t, testenv.Description{
+		Orgs: []testenv.Org{{Name: "org", Repos: []testenv.Repo{
+			libRepo(),
+			goRepo("a", goMod("github.com/org/a", "1.99", "github.com/org/lib v1.0.0", "golang.org/x/mod v0.14.0 // indirect")),
+			goRepo("b", goMod("github.com/org/b", "1.99", "github.com/org/lib v0.0.0-20200101000000-abcdefabcdef", "github.com/org/a v1.0.0")),
+		}}},
+		GoTags: testGoTags,
+	})
+
+	run := func(config MopherConfig) string {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		config.Writer = buf
+		err := MopherUsage(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	config := testConfig(env)
+	config.OutputEncode = EncodingTsv
+	checkGolden(t, "usage_tsv", run(config))
+
+	//plain text is the default output encoding
+	config.OutputEncode = EncodingText
+	checkGolden(t, "usage_tsv", run(config))
+
+	config.OutputEncode = EncodingCsv
+	config.UsageAllDependencies = true
+	checkGolden(t, "usage_all_csv", run(config))
+
+	config.Dep = "github.com/org/lib"
+	checkGolden(t, "usage_dep_csv", run(config))
+
+	config.OutputEncode = EncodingMarkdown
+	err := MopherUsage(context.Background(), config)
+	if err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"strings"
+)
+
+// normalizeGoDependencyVersion returns the commit hash of pseudo-versions (e.g. v0.0.0-20230101000000-abcdef123456
+// or v1.2.4-0.20230101000000-abcdef123456) and the unchanged version for releases, pre-releases (v1.2.0-rc.1)
+// and +incompatible versions. the second return value is true if the version is not a pseudo-version.
+func normalizeGoDependencyVersion(version string) (string, bool) {
+	if module.IsPseudoVersion(version) {
+		rev, err := module.PseudoVersionRev(version)
+		if err == nil {
+			return shorHash(rev), false
+		}
+	}
+	return version, true
+}
+
+// getExpectedDependencyVersion returns the version a dependency should use:
+// the latest tag for semantic versions and the latest main/master commit for pseudo-versions
+func getExpectedDependencyVersion(semantic bool, latest LatestCommitInfo) string {
+	if semantic {
+		return latest.LatestTag
+	}
+	return latest.MainHash
+}
+
+type Lag string
+
+const (
+	LagUpToDate    Lag = "up-to-date"
+	LagBehindPatch Lag = "behind-patch"
+	LagBehindMinor Lag = "behind-minor"
+	LagBehindMajor Lag = "behind-major"
+	LagAhead       Lag = "ahead"
+	LagUnknown     Lag = "unknown"
+)
+
+// lagOrder is used to compare lags by severity
+var lagOrder = map[Lag]int{
+	LagAhead:       0,
+	LagUpToDate:    0,
+	LagBehindPatch: 1,
+	LagBehindMinor: 2,
+	LagBehindMajor: 3,
+}
+
+// AtLeast checks if the lag is behind by at least the given lag (e.g. behind-minor is at least behind-patch).
+// unknown lags are never at least any other lag
+func (this Lag) AtLeast(lag Lag) bool {
+	if this == LagUnknown || lag == LagUnknown {
+		return false
+	}
+	return lagOrder[this] >= lagOrder[lag]
+}
+
+// getDependencyLag classifies the used version (as written in go.mod) relative to the latest commit info.
+// pseudo-versions are up-to-date if they reference the latest main/master commit, otherwise they are compared with the latest tag.
+// the lag is unknown if no comparable tag exists.
+func getDependencyLag(rawVersion string, latest LatestCommitInfo) Lag {
+	if module.IsPseudoVersion(rawVersion) {
+		rev, _ := normalizeGoDependencyVersion(rawVersion)
+		if latest.MainHash != "" && (strings.HasPrefix(latest.MainHash, rev) || strings.HasPrefix(rev, latest.MainHash)) {
+			return LagUpToDate
+		}
+	}
+	if !semver.IsValid(rawVersion) || !semver.IsValid(latest.LatestTag) {
+		return LagUnknown
+	}
+	switch cmp := semver.Compare(rawVersion, latest.LatestTag); {
+	case cmp == 0:
+		return LagUpToDate
+	case cmp > 0:
+		return LagAhead
+	case semver.Major(rawVersion) != semver.Major(latest.LatestTag):
+		return LagBehindMajor
+	case semver.MajorMinor(rawVersion) != semver.MajorMinor(latest.LatestTag):
+		return LagBehindMinor
+	default:
+		return LagBehindPatch
+	}
+}
+
+// isDependencyVersionOutdated checks if the used version (as written in go.mod) is behind the latest version.
+// pseudo-versions with an unknown lag are outdated if they don't reference the latest main/master commit.
+func isDependencyVersionOutdated(rawVersion string, latest LatestCommitInfo) bool {
+	switch lag := getDependencyLag(rawVersion, latest); lag {
+	case LagUnknown:
+		return module.IsPseudoVersion(rawVersion) && latest.MainHash != ""
+	default:
+		return lag.AtLeast(LagBehindPatch)
+	}
+}
+
+// compareVersions orders valid semantic versions by semver precedence before other versions (e.g. commit hashes),
+// which are compared as strings
+func compareVersions(a string, b string) int {
+	aValid, bValid := semver.IsValid(a), semver.IsValid(b)
+	switch {
+	case aValid && bValid:
+		if result := semver.Compare(a, b); result != 0 {
+			return result
+		}
+		return strings.Compare(a, b)
+	case aValid:
+		return -1
+	case bValid:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"testing"
+)
+
+func TestNormalizeGoDependencyVersion(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected string
+		semantic bool
+	}{
+		{version: "v1.2.3", expected: "v1.2.3", semantic: true},
+		{version: "v1.2.0-rc.1", expected: "v1.2.0-rc.1", semantic: true},
+		{version: "v1.2.0-beta-2", expected: "v1.2.0-beta-2", semantic: true},
+		{version: "v2.0.0+incompatible", expected: "v2.0.0+incompatible", semantic: true},
+		{version: "v0.0.0-20230101000000-abcdef123456", expected: "abcdef123456"},
+		{version: "v1.2.4-0.20230101000000-abcdef123456", expected: "abcdef123456"},
+		{version: "v1.2.0-rc.1.0.20230101000000-abcdef123456", expected: "abcdef123456"},
+		{version: "v2.0.1-0.20230101000000-abcdef123456+incompatible", expected: "abcdef123456"},
+	}
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			actual, semantic := normalizeGoDependencyVersion(c.version)
+			if actual != c.expected || semantic != c.semantic {
+				t.Errorf("expected %v %v, got %v %v", c.expected, c.semantic, actual, semantic)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{a: "v1.9.0", b: "v1.10.0", expected: -1},
+		{a: "v1.2.0-rc.1", b: "v1.2.0", expected: -1},
+		{a: "v1.2.0-rc.2", b: "v1.2.0-rc.10", expected: -1},
+		{a: "v2.0.0+incompatible", b: "v1.9.0", expected: 1},
+		{a: "v1.2.3", b: "abcdef123456", expected: -1},
+		{a: "abcdef123456", b: "123456abcdef", expected: 1},
+		{a: "v1.2.3", b: "v1.2.3", expected: 0},
+	}
+	for _, c := range cases {
+		t.Run(c.a+" "+c.b, func(t *testing.T) {
+			if actual := compareVersions(c.a, c.b); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetDependencyLag(t *testing.T) {
+	latest := LatestCommitInfo{LatestTag: "v1.2.3", MainHash: "abcdef123456"}
+	cases := []struct {
+		name     string
+		version  string
+		latest   LatestCommitInfo
+		expected Lag
+		outdated bool
+	}{
+		{name: "latest tag", version: "v1.2.3", latest: latest, expected: LagUpToDate},
+		{name: "patch", version: "v1.2.1", latest: latest, expected: LagBehindPatch, outdated: true},
+		{name: "minor", version: "v1.1.9", latest: latest, expected: LagBehindMinor, outdated: true},
+		{name: "major", version: "v0.9.0", latest: latest, expected: LagBehindMajor, outdated: true},
+		{name: "newer tag", version: "v1.3.0", latest: latest, expected: LagAhead},
+		{name: "pre-release of latest tag", version: "v1.2.3-rc.1", latest: latest, expected: LagBehindPatch, outdated: true},
+		{name: "pre-release after latest tag", version: "v1.3.0-rc.1", latest: latest, expected: LagAhead},
+		{name: "incompatible", version: "v2.0.0+incompatible", latest: LatestCommitInfo{LatestTag: "v2.1.0+incompatible"}, expected: LagBehindMinor, outdated: true},
+		{name: "pseudo-version of main", version: "v1.2.4-0.20230101000000-abcdef123456", latest: latest, expected: LagUpToDate},
+		{name: "pseudo-version after latest tag", version: "v1.2.4-0.20230101000000-123456abcdef", latest: latest, expected: LagAhead},
+		{name: "pseudo-version before latest tag", version: "v1.2.3-0.20230101000000-123456abcdef", latest: latest, expected: LagBehindPatch, outdated: true},
+		{name: "pseudo-version without base", version: "v0.0.0-20230101000000-123456abcdef", latest: latest, expected: LagBehindMajor, outdated: true},
+		{name: "pseudo-version without tags", version: "v0.0.0-20230101000000-123456abcdef", latest: LatestCommitInfo{MainHash: "abcdef123456"}, expected: LagUnknown, outdated: true},
+		{name: "pseudo-version of main without tags", version: "v0.0.0-20230101000000-abcdef123456", latest: LatestCommitInfo{MainHash: "abcdef123456"}, expected: LagUpToDate},
+		{name: "no tags", version: "v1.2.3", latest: LatestCommitInfo{MainHash: "abcdef123456"}, expected: LagUnknown},
+		{name: "invalid version", version: "master", latest: latest, expected: LagUnknown},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := getDependencyLag(c.version, c.latest); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+			if actual := isDependencyVersionOutdated(c.version, c.latest); actual != c.outdated {
+				t.Errorf("expected outdated=%v, got %v", c.outdated, actual)
+			}
+		})
+	}
+}
+
+func TestLagAtLeast(t *testing.T) {
+	cases := []struct {
+		lag      Lag
+		min      Lag
+		expected bool
+	}{
+		{lag: LagBehindMinor, min: LagBehindPatch, expected: true},
+		{lag: LagBehindMinor, min: LagBehindMinor, expected: true},
+		{lag: LagBehindPatch, min: LagBehindMinor, expected: false},
+		{lag: LagBehindMajor, min: LagBehindMinor, expected: true},
+		{lag: LagAhead, min: LagBehindPatch, expected: false},
+		{lag: LagUnknown, min: LagBehindPatch, expected: false},
+		{lag: LagBehindMajor, min: LagUnknown, expected: false},
+	}
+	for _, c := range cases {
+		t.Run(string(c.lag)+" "+string(c.min), func(t *testing.T) {
+			if actual := c.lag.AtLeast(c.min); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}